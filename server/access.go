@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	accessLevelSystemAdmin  = "system_admin"
+	accessLevelTeamAdmin    = "team_admin"
+	accessLevelChannelAdmin = "channel_admin"
+	accessLevelEveryone     = "everyone"
+
+	// operationList is the access operation of the list commands. The other
+	// access operations share the names of the journaled operations.
+	operationList = "list"
+)
+
+// accessOperations are the operations that can be restricted to an access
+// level, in the order they are reported to the webapp.
+var accessOperations = []string{operationMove, operationCopy, operationAttach, operationList}
+
+func isValidAccessLevel(level string) bool {
+	switch level {
+	case "", accessLevelSystemAdmin, accessLevelTeamAdmin, accessLevelChannelAdmin, accessLevelEveryone:
+		return true
+	}
+
+	return false
+}
+
+// getAccessOperationDenied returns the message shown to users who don't have
+// the access level required for an operation.
+func getAccessOperationDenied(operation, level string) string {
+	var who string
+	switch level {
+	case accessLevelSystemAdmin:
+		who = "system admins"
+	case accessLevelTeamAdmin:
+		who = "team admins"
+	case accessLevelChannelAdmin:
+		who = "channel admins of the channel the messages are in"
+	}
+
+	var what string
+	switch operation {
+	case operationAttach:
+		what = "attach and detach messages"
+	case operationList:
+		what = "list channels, messages and threads"
+	default:
+		what = fmt.Sprintf("%s messages", operation)
+	}
+
+	return fmt.Sprintf("Permission denied. Wrangler is configured to only allow %s to %s.", who, what)
+}
+
+// hasOperationAccess returns if the user has the access level the plugin
+// configuration requires for the operation in the given source channel.
+// Direct and group message channels don't belong to a team, so team admins
+// are checked against the provided team instead.
+func (p *Plugin) hasOperationAccess(userID, operation, channelID, teamID string) bool {
+	switch p.getConfiguration().AccessLevel(operation) {
+	case accessLevelSystemAdmin:
+		return p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM)
+	case accessLevelTeamAdmin:
+		if len(channelID) != 0 {
+			channel, appErr := p.API.GetChannel(channelID)
+			if appErr != nil {
+				return false
+			}
+			if len(channel.TeamId) != 0 {
+				teamID = channel.TeamId
+			}
+		}
+		if len(teamID) == 0 {
+			return p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM)
+		}
+		return p.API.HasPermissionToTeam(userID, teamID, model.PERMISSION_MANAGE_TEAM)
+	case accessLevelChannelAdmin:
+		if len(channelID) == 0 {
+			return p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM)
+		}
+		return p.API.HasPermissionToChannel(userID, channelID, model.PERMISSION_MANAGE_CHANNEL_ROLES)
+	}
+
+	return true
+}
+
+// getAllowedOperations returns the operations the user has access to. When no
+// channel or team is provided, operations that depend on the user's role in
+// the channel or team are reported as allowed and are checked again when they
+// are run.
+func (p *Plugin) getAllowedOperations(userID, channelID, teamID string) []string {
+	config := p.getConfiguration()
+
+	allowed := []string{}
+	for _, operation := range accessOperations {
+		level := config.AccessLevel(operation)
+		if len(channelID) == 0 && (level == accessLevelChannelAdmin || (level == accessLevelTeamAdmin && len(teamID) == 0)) {
+			allowed = append(allowed, operation)
+			continue
+		}
+		if p.hasOperationAccess(userID, operation, channelID, teamID) {
+			allowed = append(allowed, operation)
+		}
+	}
+
+	return allowed
+}
+
+// parseNameList splits a comma separated list of usernames or group names.
+// Entries are trimmed of whitespace and of a leading @.
+func parseNameList(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if len(name) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsGroup returns if the group is in the list. Groups synced from LDAP
+// don't always have a name, so they can also be listed by display name.
+func containsGroup(names []string, group *model.Group) bool {
+	if group.Name != nil && containsName(names, *group.Name) {
+		return true
+	}
+
+	return containsName(names, group.DisplayName)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHasOperationAccess(t *testing.T) {
+	systemAdminID := model.NewId()
+	teamAdminID := model.NewId()
+	channelAdminID := model.NewId()
+	userID := model.NewId()
+	team := &model.Team{Id: model.NewId()}
+	channel := &model.Channel{Id: model.NewId(), TeamId: team.Id}
+	directChannel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_DIRECT}
+
+	api := &plugintest.API{}
+	api.On("GetChannel", channel.Id).Return(channel, nil)
+	api.On("GetChannel", directChannel.Id).Return(directChannel, nil)
+	api.On("HasPermissionTo", systemAdminID, model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", mock.AnythingOfType("string"), model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	for _, id := range []string{systemAdminID, teamAdminID} {
+		api.On("HasPermissionToTeam", id, team.Id, model.PERMISSION_MANAGE_TEAM).Return(true)
+	}
+	api.On("HasPermissionToTeam", mock.AnythingOfType("string"), mock.AnythingOfType("string"), model.PERMISSION_MANAGE_TEAM).Return(false)
+	for _, id := range []string{systemAdminID, teamAdminID, channelAdminID} {
+		api.On("HasPermissionToChannel", id, channel.Id, model.PERMISSION_MANAGE_CHANNEL_ROLES).Return(true)
+	}
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), model.PERMISSION_MANAGE_CHANNEL_ROLES).Return(false)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	users := []string{systemAdminID, teamAdminID, channelAdminID, userID}
+
+	tests := []struct {
+		level    string
+		expected []bool
+	}{
+		{"", []bool{true, true, true, true}},
+		{accessLevelEveryone, []bool{true, true, true, true}},
+		{accessLevelChannelAdmin, []bool{true, true, true, false}},
+		{accessLevelTeamAdmin, []bool{true, true, false, false}},
+		{accessLevelSystemAdmin, []bool{true, false, false, false}},
+	}
+	for _, test := range tests {
+		t.Run(test.level, func(t *testing.T) {
+			plugin.setConfiguration(&configuration{MoveAccessLevel: test.level})
+			for i, user := range users {
+				assert.Equal(t, test.expected[i], plugin.hasOperationAccess(user, operationMove, channel.Id, ""), "user %d", i)
+				assert.True(t, plugin.hasOperationAccess(user, operationCopy, channel.Id, ""))
+			}
+		})
+	}
+
+	t.Run("team admins in direct message channels", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{AttachAccessLevel: accessLevelTeamAdmin})
+		assert.True(t, plugin.hasOperationAccess(teamAdminID, operationAttach, directChannel.Id, team.Id))
+		assert.False(t, plugin.hasOperationAccess(teamAdminID, operationAttach, directChannel.Id, ""))
+		assert.False(t, plugin.hasOperationAccess(userID, operationAttach, directChannel.Id, team.Id))
+	})
+
+	t.Run("allowed operations", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{
+			MoveAccessLevel:   accessLevelSystemAdmin,
+			CopyAccessLevel:   accessLevelTeamAdmin,
+			AttachAccessLevel: accessLevelChannelAdmin,
+		})
+
+		assert.Equal(t, []string{operationMove, operationCopy, operationAttach, operationList}, plugin.getAllowedOperations(systemAdminID, channel.Id, ""))
+		assert.Equal(t, []string{operationCopy, operationAttach, operationList}, plugin.getAllowedOperations(teamAdminID, channel.Id, ""))
+		assert.Equal(t, []string{operationList}, plugin.getAllowedOperations(userID, channel.Id, ""))
+		assert.Equal(t, []string{operationAttach, operationList}, plugin.getAllowedOperations(userID, "", team.Id))
+		assert.Equal(t, []string{operationCopy, operationAttach, operationList}, plugin.getAllowedOperations(userID, "", ""))
+	})
+}
@@ -1,23 +1,32 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 )
 
 const (
 	// API V1
 	routeAPISettings = "/api/v1/settings"
+	routeAPIAudit    = "/api/v1/audit"
 
 	routeProfileImage = "/profile.png"
+
+	auditFormatJSON     = "json"
+	auditFormatCSV      = "csv"
+	defaultAuditPerPage = 1000
+	maxAuditPerPage     = 10000
 )
 
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
@@ -39,6 +48,8 @@ func (p *Plugin) serveHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Req
 	switch path := r.URL.Path; path {
 	case routeAPISettings:
 		return p.handleRouteAPISettings(w, r)
+	case routeAPIAudit:
+		return p.handleRouteAPIAudit(w, r)
 	case routeProfileImage:
 		return p.handleProfileImage(w, r)
 	}
@@ -58,19 +69,202 @@ func (p *Plugin) handleRouteAPISettings(w http.ResponseWriter, r *http.Request)
 	}
 
 	var enabled bool
-	if p.getConfiguration().EnableWebUI && p.authorizedPluginUser(mattermostUserID) {
-		enabled = true
+	allowedOperations := []string{}
+	if p.authorizedPluginUser(mattermostUserID) {
+		enabled = p.getConfiguration().EnableWebUI
+		query := r.URL.Query()
+		allowedOperations = p.getAllowedOperations(mattermostUserID, query.Get("channel_id"), query.Get("team_id"))
 	}
 
 	return respondJSON(w,
 		struct {
-			EnableWebUI bool `json:"enable_web_ui"`
+			EnableWebUI       bool     `json:"enable_web_ui"`
+			AllowedOperations []string `json:"allowed_operations"`
 		}{
-			EnableWebUI: enabled,
+			EnableWebUI:       enabled,
+			AllowedOperations: allowedOperations,
 		},
 	)
 }
 
+// handleRouteAPIAudit exports the audit log to system admins. Entries are
+// returned newest first and are written as they are read from the KV store,
+// so large exports are paged with the page and per_page query parameters
+// until a page with fewer than per_page entries is returned.
+func (p *Plugin) handleRouteAPIAudit(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return respondErr(w, http.StatusMethodNotAllowed,
+			errors.Errorf("method %s is not allowed, must be GET", r.Method))
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondErr(w, http.StatusUnauthorized, errors.New("not authorized"))
+	}
+	if !p.API.HasPermissionTo(mattermostUserID, model.PERMISSION_MANAGE_SYSTEM) {
+		return respondErr(w, http.StatusForbidden, errors.New("only system admins can export the audit log"))
+	}
+
+	query := r.URL.Query()
+	filter := &auditLogFilter{
+		UserID:    query.Get("user_id"),
+		ChannelID: query.Get("channel_id"),
+		Operation: query.Get("operation"),
+	}
+
+	var err error
+	filter.Since, err = getQueryInt64(query.Get("since"), 0)
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.Wrap(err, "invalid since"))
+	}
+	filter.Until, err = getQueryInt64(query.Get("until"), 0)
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.Wrap(err, "invalid until"))
+	}
+	page, err := getQueryInt64(query.Get("page"), 0)
+	if err != nil || page < 0 {
+		return respondErr(w, http.StatusBadRequest, errors.New("page must be a positive number"))
+	}
+	perPage, err := getQueryInt64(query.Get("per_page"), defaultAuditPerPage)
+	if err != nil || perPage < 1 || perPage > maxAuditPerPage {
+		return respondErr(w, http.StatusBadRequest, errors.Errorf("per_page must be between 1 and %d", maxAuditPerPage))
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = auditFormatJSON
+	}
+
+	var writer auditEntryWriter
+	switch format {
+	case auditFormatJSON:
+		w.Header().Set("Content-Type", "application/json")
+		writer = &auditJSONWriter{w: w}
+	case auditFormatCSV:
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=wrangler-audit.csv")
+		writer = &auditCSVWriter{w: csv.NewWriter(w)}
+	default:
+		return respondErr(w, http.StatusBadRequest, errors.Errorf("format must be %s or %s", auditFormatJSON, auditFormatCSV))
+	}
+
+	flusher, _ := w.(http.Flusher)
+	skip := page * perPage
+	var written int64
+	writeErr := writer.begin()
+	if writeErr != nil {
+		return http.StatusInternalServerError, errors.WithMessage(writeErr, "failed to write response")
+	}
+
+	err = p.forEachAuditEntry(filter, func(entry *AuditEntry) bool {
+		if skip > 0 {
+			skip--
+			return true
+		}
+		writeErr = writer.write(entry)
+		if writeErr != nil {
+			return false
+		}
+		written++
+		if flusher != nil && written%100 == 0 {
+			flusher.Flush()
+		}
+
+		return written < perPage
+	})
+	if writeErr == nil {
+		writeErr = writer.end()
+	}
+
+	// The status has already been sent with the first entry, so errors can
+	// only be logged and reported by cutting the export short.
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "failed to export audit log")
+	}
+	if writeErr != nil {
+		return http.StatusInternalServerError, errors.WithMessage(writeErr, "failed to write response")
+	}
+
+	return http.StatusOK, nil
+}
+
+// auditEntryWriter writes an audit log export one entry at a time.
+type auditEntryWriter interface {
+	begin() error
+	write(entry *AuditEntry) error
+	end() error
+}
+
+// auditJSONWriter writes audit log entries as a JSON array.
+type auditJSONWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (aw *auditJSONWriter) begin() error {
+	_, err := io.WriteString(aw.w, "[")
+	return err
+}
+
+func (aw *auditJSONWriter) write(entry *AuditEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if aw.count > 0 {
+		_, err = io.WriteString(aw.w, ",")
+		if err != nil {
+			return err
+		}
+	}
+	aw.count++
+
+	_, err = aw.w.Write(b)
+	return err
+}
+
+func (aw *auditJSONWriter) end() error {
+	_, err := io.WriteString(aw.w, "]")
+	return err
+}
+
+// auditCSVWriter writes audit log entries as CSV with a header row. Post IDs
+// are joined with spaces into a single column.
+type auditCSVWriter struct {
+	w *csv.Writer
+}
+
+func (aw *auditCSVWriter) begin() error {
+	return aw.w.Write([]string{"id", "create_at", "user_id", "operation", "source_channel_id", "target_channel_id", "post_count", "post_ids", "outcome"})
+}
+
+func (aw *auditCSVWriter) write(entry *AuditEntry) error {
+	err := aw.w.Write([]string{
+		entry.ID,
+		strconv.FormatInt(entry.CreateAt, 10),
+		entry.UserID,
+		entry.Operation,
+		entry.SourceChannelID,
+		entry.TargetChannelID,
+		strconv.Itoa(entry.PostCount),
+		strings.Join(entry.PostIDs, " "),
+		entry.Outcome,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Pass each row on to the response instead of holding it in the csv
+	// writer's buffer.
+	aw.w.Flush()
+	return aw.w.Error()
+}
+
+func (aw *auditCSVWriter) end() error {
+	aw.w.Flush()
+	return aw.w.Error()
+}
+
 func (p *Plugin) handleProfileImage(w http.ResponseWriter, r *http.Request) (int, error) {
 	bundlePath, err := p.API.GetBundlePath()
 	if err != nil {
@@ -111,6 +305,16 @@ func respondJSON(w http.ResponseWriter, obj interface{}) (int, error) {
 	return http.StatusOK, nil
 }
 
+// getQueryInt64 parses an integer query parameter, returning the default
+// value when it is empty.
+func getQueryInt64(value string, defaultValue int64) (int64, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
 func decodeJSON(obj interface{}, body io.ReadCloser) error {
 	decoder := json.NewDecoder(body)
 	err := decoder.Decode(&obj)
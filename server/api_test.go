@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRouteAPISettings(t *testing.T) {
+	adminID := model.NewId()
+	userID := model.NewId()
+
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", adminID, model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", mock.AnythingOfType("string"), model.PERMISSION_MANAGE_SYSTEM).Return(false)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{
+		EnableWebUI:     true,
+		MoveAccessLevel: accessLevelSystemAdmin,
+	})
+
+	request := func(userID string) map[string]interface{} {
+		r := httptest.NewRequest(http.MethodGet, routeAPISettings, nil)
+		r.Header.Set("Mattermost-User-Id", userID)
+		w := httptest.NewRecorder()
+		plugin.handleRouteAPISettings(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	t.Run("system admin", func(t *testing.T) {
+		result := request(adminID)
+		assert.Equal(t, true, result["enable_web_ui"])
+		assert.Equal(t, []interface{}{"move", "copy", "attach", "list"}, result["allowed_operations"])
+	})
+
+	t.Run("user", func(t *testing.T) {
+		result := request(userID)
+		assert.Equal(t, true, result["enable_web_ui"])
+		assert.Equal(t, []interface{}{"copy", "attach", "list"}, result["allowed_operations"])
+	})
+}
+
+func TestHandleRouteAPIAudit(t *testing.T) {
+	adminID := model.NewId()
+	userID := model.NewId()
+	channelID := model.NewId()
+
+	api := &plugintest.API{}
+	mockKVStore(api)
+	api.On("HasPermissionTo", adminID, model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", mock.AnythingOfType("string"), model.PERMISSION_MANAGE_SYSTEM).Return(false)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	var entries []*AuditEntry
+	for i := 0; i < 5; i++ {
+		entry := newAuditEntry(operationMove, userID, channelID, model.NewId(), []*model.Post{{Id: model.NewId()}, {Id: model.NewId()}})
+		entry.CreateAt = int64(1000 * (i + 1))
+		entry.succeeded()
+		plugin.recordAuditEntry(entry)
+		entries = append(entries, entry)
+	}
+	otherEntry := newAuditEntry(operationCopy, adminID, model.NewId(), model.NewId(), nil)
+	otherEntry.CreateAt = 10000
+	plugin.recordAuditEntry(otherEntry)
+
+	request := func(userID, query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, routeAPIAudit+"?"+query, nil)
+		if userID != "" {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		w := httptest.NewRecorder()
+		plugin.handleRouteAPIAudit(w, r)
+		return w
+	}
+
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) []*AuditEntry {
+		var result []*AuditEntry
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	t.Run("not logged in", func(t *testing.T) {
+		w := request("", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("not a system admin", func(t *testing.T) {
+		w := request(userID, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"since=yesterday", "until=x", "page=-1", "per_page=0", "per_page=10001", "format=xml"} {
+			w := request(adminID, query)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("every entry", func(t *testing.T) {
+		w := request(adminID, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		result := decode(t, w)
+		require.Len(t, result, 6)
+		assert.Equal(t, otherEntry.ID, result[0].ID)
+		assert.Equal(t, entries[0].ID, result[5].ID)
+		assert.Equal(t, entries[0].PostIDs, result[5].PostIDs)
+	})
+
+	t.Run("no entries", func(t *testing.T) {
+		w := request(adminID, "operation=split")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "[]", w.Body.String())
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		w := request(adminID, "user_id="+userID+"&channel_id="+channelID+"&operation=move&since=2000&until=4000")
+		require.Equal(t, http.StatusOK, w.Code)
+		result := decode(t, w)
+		require.Len(t, result, 3)
+		assert.Equal(t, entries[3].ID, result[0].ID)
+		assert.Equal(t, entries[1].ID, result[2].ID)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var ids []string
+		for _, page := range []string{"0", "1", "2"} {
+			w := request(adminID, "user_id="+userID+"&per_page=2&page="+page)
+			require.Equal(t, http.StatusOK, w.Code)
+			for _, entry := range decode(t, w) {
+				ids = append(ids, entry.ID)
+			}
+		}
+		assert.Equal(t, []string{entries[4].ID, entries[3].ID, entries[2].ID, entries[1].ID, entries[0].ID}, ids)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := request(adminID, "format=csv&per_page=1&page=1")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, []string{
+			entries[4].ID,
+			"5000",
+			userID,
+			operationMove,
+			channelID,
+			entries[4].TargetChannelID,
+			"2",
+			strings.Join(entries[4].PostIDs, " "),
+			auditOutcomeSucceeded,
+		}, records[1])
+	})
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	auditKeyPrefix = "audit_"
+
+	// auditDaysKey lists the days that have audit log entries, and each of
+	// those days has an index key that lists the keys of its entries. Days
+	// are in UTC and formatted as auditDayFormat.
+	auditDaysKey      = "audit_days"
+	auditDayKeyPrefix = "audit_day_"
+	auditDayFormat    = "20060102"
+
+	auditOutcomeSucceeded = "succeeded"
+	auditOutcomeFailed    = "failed"
+)
+
+// AuditEntry records a single wrangle operation in the audit log. Entries are
+// kept in the plugin KV store under keys that start with their creation time
+// and are indexed by the day they were created on, so that the log can be
+// browsed by time without loading every entry.
+type AuditEntry struct {
+	ID              string   `json:"id"`
+	CreateAt        int64    `json:"create_at"`
+	UserID          string   `json:"user_id"`
+	Operation       string   `json:"operation"`
+	SourceChannelID string   `json:"source_channel_id"`
+	TargetChannelID string   `json:"target_channel_id"`
+	PostIDs         []string `json:"post_ids"`
+	PostCount       int      `json:"post_count"`
+	Outcome         string   `json:"outcome"`
+}
+
+// auditLogFilter selects audit log entries. Empty fields match every entry.
+type auditLogFilter struct {
+	UserID    string
+	ChannelID string
+	Operation string
+	Since     int64
+	Until     int64
+}
+
+func auditKey(createAt int64, id string) string {
+	return fmt.Sprintf("%s%013d_%s", auditKeyPrefix, createAt, id)
+}
+
+// auditKeyCreateAt returns the creation time stored in an audit entry key.
+func auditKeyCreateAt(key string) (int64, error) {
+	parts := strings.SplitN(strings.TrimPrefix(key, auditKeyPrefix), "_", 2)
+
+	return strconv.ParseInt(parts[0], 10, 64)
+}
+
+func auditDay(createAt int64) string {
+	return time.Unix(0, createAt*int64(time.Millisecond)).UTC().Format(auditDayFormat)
+}
+
+func auditDayKey(day string) string {
+	return auditDayKeyPrefix + day
+}
+
+// newAuditEntry starts an audit log entry for an operation on the given
+// posts. The entry is failed until it is marked as succeeded.
+func newAuditEntry(operation, userID, sourceChannelID, targetChannelID string, posts []*model.Post) *AuditEntry {
+	entry := &AuditEntry{
+		ID:              model.NewId(),
+		CreateAt:        model.GetMillis(),
+		UserID:          userID,
+		Operation:       operation,
+		SourceChannelID: sourceChannelID,
+		TargetChannelID: targetChannelID,
+		PostCount:       len(posts),
+		Outcome:         auditOutcomeFailed,
+	}
+	for _, post := range posts {
+		entry.PostIDs = append(entry.PostIDs, post.Id)
+	}
+
+	return entry
+}
+
+func (e *AuditEntry) succeeded() {
+	e.Outcome = auditOutcomeSucceeded
+}
+
+// matches returns if the entry is selected by the filter. A channel matches
+// either the source or the target channel of the operation.
+func (f *auditLogFilter) matches(entry *AuditEntry) bool {
+	if len(f.UserID) != 0 && entry.UserID != f.UserID {
+		return false
+	}
+	if len(f.ChannelID) != 0 && entry.SourceChannelID != f.ChannelID && entry.TargetChannelID != f.ChannelID {
+		return false
+	}
+	if len(f.Operation) != 0 && entry.Operation != f.Operation {
+		return false
+	}
+
+	return f.matchesTime(entry.CreateAt)
+}
+
+func (f *auditLogFilter) matchesTime(createAt int64) bool {
+	if f.Since != 0 && createAt < f.Since {
+		return false
+	}
+	if f.Until != 0 && createAt > f.Until {
+		return false
+	}
+
+	return true
+}
+
+// recordAuditEntry writes the entry to the audit log. It is deferred by the
+// operations it records, so failures are only logged.
+func (p *Plugin) recordAuditEntry(entry *AuditEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		p.API.LogError("Unable to marshal audit log entry", "error", err.Error())
+		return
+	}
+
+	key := auditKey(entry.CreateAt, entry.ID)
+	appErr := p.API.KVSet(key, b)
+	if appErr != nil {
+		p.API.LogError("Unable to save audit log entry",
+			"operation", entry.Operation,
+			"user_id", entry.UserID,
+			"error", appErr.Error(),
+		)
+		return
+	}
+
+	err = p.indexAuditEntry(entry.CreateAt, key)
+	if err != nil {
+		p.API.LogError("Unable to index audit log entry",
+			"operation", entry.Operation,
+			"user_id", entry.UserID,
+			"error", err.Error(),
+		)
+	}
+}
+
+// indexAuditEntry adds the entry key to the index of the day it was created
+// on, and adds the day to the list of days with entries if it is new.
+func (p *Plugin) indexAuditEntry(createAt int64, key string) error {
+	day := auditDay(createAt)
+	newDay, err := p.updateKVStringList(auditDayKey(day), func(keys []string) []string {
+		return append(keys, key)
+	})
+	if err != nil || !newDay {
+		return err
+	}
+
+	_, err = p.updateKVStringList(auditDaysKey, func(days []string) []string {
+		for _, existing := range days {
+			if existing == day {
+				return days
+			}
+		}
+		return append(days, day)
+	})
+
+	return err
+}
+
+// listAuditDays returns the days with audit log entries within the filter's
+// time range, newest first.
+func (p *Plugin) listAuditDays(filter *auditLogFilter) ([]string, error) {
+	days, _, err := p.getKVStringList(auditDaysKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	for _, day := range days {
+		if filter.Until != 0 && day > auditDay(filter.Until) {
+			continue
+		}
+		if filter.Since != 0 && day < auditDay(filter.Since) {
+			continue
+		}
+		selected = append(selected, day)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(selected)))
+
+	return selected, nil
+}
+
+// listAuditEntryKeys returns the keys of the audit log entries created on the
+// day within the filter's time range, newest first.
+func (p *Plugin) listAuditEntryKeys(day string, filter *auditLogFilter) ([]string, error) {
+	dayKeys, _, err := p.getKVStringList(auditDayKey(day))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, key := range dayKeys {
+		createAt, err := auditKeyCreateAt(key)
+		if err != nil || !filter.matchesTime(createAt) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	return keys, nil
+}
+
+// forEachAuditEntry calls fn with every audit log entry selected by the
+// filter, newest first, until fn returns false. Entries are loaded one day at
+// a time so that large logs don't have to be held in memory.
+func (p *Plugin) forEachAuditEntry(filter *auditLogFilter, fn func(*AuditEntry) bool) error {
+	days, err := p.listAuditDays(filter)
+	if err != nil {
+		return err
+	}
+
+	for _, day := range days {
+		keys, err := p.listAuditEntryKeys(day, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			b, appErr := p.API.KVGet(key)
+			if appErr != nil {
+				return errors.Wrap(appErr, "unable to get audit log entry")
+			}
+			if b == nil {
+				continue
+			}
+
+			var entry AuditEntry
+			err = json.Unmarshal(b, &entry)
+			if err != nil {
+				return errors.Wrap(err, "unable to unmarshal audit log entry")
+			}
+			if !filter.matches(&entry) {
+				continue
+			}
+			if !fn(&entry) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	api := &plugintest.API{}
+	mockKVStore(api)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	user1 := model.NewId()
+	user2 := model.NewId()
+	channel1 := model.NewId()
+	channel2 := model.NewId()
+	channel3 := model.NewId()
+
+	newEntry := func(createAt int64, operation, userID, sourceChannelID, targetChannelID string) *AuditEntry {
+		entry := newAuditEntry(operation, userID, sourceChannelID, targetChannelID, []*model.Post{{Id: model.NewId()}})
+		entry.CreateAt = createAt
+		return entry
+	}
+
+	entry1 := newEntry(1000, operationMove, user1, channel1, channel2)
+	entry1.succeeded()
+	entry2 := newEntry(2000, operationCopy, user2, channel2, channel3)
+	entry3 := newEntry(3000, operationMerge, user1, channel3, channel3)
+	entry3.succeeded()
+	day := int64(24 * 60 * 60 * 1000)
+	entry4 := newEntry(2*day+1000, operationSplit, user2, channel1, channel1)
+	for _, entry := range []*AuditEntry{entry2, entry4, entry3, entry1} {
+		plugin.recordAuditEntry(entry)
+	}
+
+	list := func(filter *auditLogFilter, limit int) []*AuditEntry {
+		var entries []*AuditEntry
+		err := plugin.forEachAuditEntry(filter, func(entry *AuditEntry) bool {
+			entries = append(entries, entry)
+			return len(entries) < limit
+		})
+		require.NoError(t, err)
+		return entries
+	}
+
+	t.Run("newest first", func(t *testing.T) {
+		entries := list(&auditLogFilter{}, 10)
+		require.Len(t, entries, 4)
+		assert.Equal(t, []string{entry4.ID, entry3.ID, entry2.ID, entry1.ID}, []string{entries[0].ID, entries[1].ID, entries[2].ID, entries[3].ID})
+		assert.Equal(t, auditOutcomeSucceeded, entries[1].Outcome)
+		assert.Equal(t, auditOutcomeFailed, entries[2].Outcome)
+		assert.Equal(t, entry1.PostIDs, entries[3].PostIDs)
+		assert.Equal(t, 1, entries[3].PostCount)
+	})
+
+	t.Run("stops early", func(t *testing.T) {
+		entries := list(&auditLogFilter{}, 1)
+		require.Len(t, entries, 1)
+		assert.Equal(t, entry4.ID, entries[0].ID)
+	})
+
+	t.Run("filter by user", func(t *testing.T) {
+		entries := list(&auditLogFilter{UserID: user1}, 10)
+		require.Len(t, entries, 2)
+		assert.Equal(t, entry3.ID, entries[0].ID)
+		assert.Equal(t, entry1.ID, entries[1].ID)
+	})
+
+	t.Run("filter by channel matches source and target", func(t *testing.T) {
+		entries := list(&auditLogFilter{ChannelID: channel2}, 10)
+		require.Len(t, entries, 2)
+		assert.Equal(t, entry2.ID, entries[0].ID)
+		assert.Equal(t, entry1.ID, entries[1].ID)
+	})
+
+	t.Run("filter by operation", func(t *testing.T) {
+		entries := list(&auditLogFilter{Operation: operationCopy}, 10)
+		require.Len(t, entries, 1)
+		assert.Equal(t, entry2.ID, entries[0].ID)
+	})
+
+	t.Run("filter by time", func(t *testing.T) {
+		entries := list(&auditLogFilter{Since: 1500, Until: 2500}, 10)
+		require.Len(t, entries, 1)
+		assert.Equal(t, entry2.ID, entries[0].ID)
+
+		entries = list(&auditLogFilter{Since: day}, 10)
+		require.Len(t, entries, 1)
+		assert.Equal(t, entry4.ID, entries[0].ID)
+	})
+
+	t.Run("only the index keys are read", func(t *testing.T) {
+		list(&auditLogFilter{}, 10)
+		api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
@@ -14,16 +15,36 @@ const helpText = `Wrangler Plugin - Slash Command Help
 
 %s
 
-/wrangler attach message [MESSAGE_ID_TO_ATTACH] [ROOT_MESSAGE_ID]
-  Attach a given message to a thread in the same channel
-    - Obtain the message IDs by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
+%s
+
+%s
+
+%s
+
+%s
+
+%s
+%s
+%s
 
+%s
+
+%s
+
+/wrangler history [flags]
+  Browse the log of wrangler actions. System admins see every action and other users only their own
+    Flags:
+%s
 /wrangler list channels [flags]
   List the IDs of all channels you have joined
 	Flags:
 %s
 /wrangler list messages [flags]
-  List the IDs of recent messages in this channel
+  List the IDs of recent messages in this channel, along with their author, time and reply count
+    Flags:
+%s
+/wrangler list threads [flags]
+  List the most recently active threads in this channel, along with their reply count, participant count and last activity
     Flags:
 %s
 /wrangler info
@@ -33,9 +54,20 @@ func getHelp() string {
 	return codeBlock(fmt.Sprintf(
 		helpText,
 		getMoveThreadUsage(),
-		copyThreadUsage,
+		getCopyThreadUsage(),
+		getMoveMessagesUsage(),
+		getCopyMessagesUsage(),
+		getMergeThreadUsage(),
+		splitThreadUsage,
+		getAttachMessageUsage(),
+		getDetachMessageUsage(),
+		traceUsage,
+		jobsUsage,
+		undoUsage,
+		getHistoryFlagSet().FlagUsages(),
 		getListChannelsFlagSet().FlagUsages(),
 		getListMessagesFlagSet().FlagUsages(),
+		getListThreadsFlagSet().FlagUsages(),
 	))
 }
 
@@ -45,7 +77,7 @@ func getCommand(autocomplete bool) *model.Command {
 		DisplayName:      "Wrangler",
 		Description:      "Manage Mattermost messages!",
 		AutoComplete:     autocomplete,
-		AutoCompleteDesc: "Available commands: move thread, copy thread, attach message, list messages, list channels, info",
+		AutoCompleteDesc: "Available commands: move thread, move messages, copy thread, copy messages, merge thread, split thread, attach message, detach message, trace, jobs, undo, history, list messages, list threads, list channels, info",
 		AutoCompleteHint: "[command]",
 		AutocompleteData: getAutocompleteData(),
 	}
@@ -75,6 +107,11 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	command := stringArgs[1]
 
 	var handler func([]string, *model.CommandArgs) (*model.CommandResponse, bool, error)
+	var accessOperation string
+
+	// Commands are run from the channel containing the messages they act on,
+	// so access is checked against the channel the command was run in.
+	accessChannelID := args.ChannelId
 
 	switch command {
 	case "move":
@@ -85,6 +122,11 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		switch stringArgs[2] {
 		case "thread":
 			handler = p.runMoveThreadCommand
+			accessOperation = operationMove
+			stringArgs = stringArgs[3:]
+		case "messages":
+			handler = p.runMoveMessagesCommand
+			accessOperation = operationMove
 			stringArgs = stringArgs[3:]
 		}
 	case "copy":
@@ -95,6 +137,33 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		switch stringArgs[2] {
 		case "thread":
 			handler = p.runCopyThreadCommand
+			accessOperation = operationCopy
+			stringArgs = stringArgs[3:]
+		case "messages":
+			handler = p.runCopyMessagesCommand
+			accessOperation = operationCopy
+			stringArgs = stringArgs[3:]
+		}
+	case "merge":
+		if len(stringArgs) < 3 {
+			break
+		}
+
+		switch stringArgs[2] {
+		case "thread":
+			handler = p.runMergeThreadCommand
+			accessOperation = operationMove
+			stringArgs = stringArgs[3:]
+		}
+	case "split":
+		if len(stringArgs) < 3 {
+			break
+		}
+
+		switch stringArgs[2] {
+		case "thread":
+			handler = p.runSplitThreadCommand
+			accessOperation = operationMove
 			stringArgs = stringArgs[3:]
 		}
 	case "attach":
@@ -105,8 +174,44 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		switch stringArgs[2] {
 		case "message":
 			handler = p.runAttachMessageCommand
+			accessOperation = operationAttach
+			stringArgs = stringArgs[3:]
+		}
+	case "detach":
+		if len(stringArgs) < 3 {
+			break
+		}
+
+		switch stringArgs[2] {
+		case "message":
+			handler = p.runDetachMessageCommand
+			accessOperation = operationAttach
 			stringArgs = stringArgs[3:]
 		}
+	case "trace":
+		handler = p.runTraceCommand
+		stringArgs = stringArgs[2:]
+	case "jobs":
+		handler = p.runJobsCommand
+		stringArgs = stringArgs[2:]
+	case "undo":
+		handler = p.runUndoCommand
+		stringArgs = stringArgs[2:]
+
+		// An undo is limited in the same way as the operation it reverts,
+		// in the channel the messages were wrangled to.
+		record, err := p.getUndoRecord(args.UserId)
+		if err != nil {
+			p.API.LogError(err.Error())
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "An unknown error occurred. Please talk to your administrator for help."), nil
+		}
+		if record != nil {
+			accessOperation = record.Operation
+			accessChannelID = record.TargetChannelID
+		}
+	case "history":
+		handler = p.runHistoryCommand
+		stringArgs = stringArgs[2:]
 	case "list":
 		if len(stringArgs) < 3 {
 			break
@@ -115,9 +220,15 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		switch stringArgs[2] {
 		case "channels":
 			handler = p.runListChannelsCommand
+			accessOperation = operationList
 			stringArgs = stringArgs[3:]
 		case "messages":
 			handler = p.runListMessagesCommand
+			accessOperation = operationList
+			stringArgs = stringArgs[3:]
+		case "threads":
+			handler = p.runListThreadsCommand
+			accessOperation = operationList
 			stringArgs = stringArgs[3:]
 		}
 	case "info":
@@ -129,8 +240,30 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getHelp()), nil
 	}
 
+	if len(accessOperation) != 0 && !p.hasOperationAccess(args.UserId, accessOperation, accessChannelID, args.TeamId) {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getAccessOperationDenied(accessOperation, p.getConfiguration().AccessLevel(accessOperation))), nil
+	}
+
+	now := time.Now()
+	if len(accessOperation) != 0 {
+		denial, err := p.checkRateLimit(accessOperation, args.UserId, now)
+		if err != nil {
+			p.API.LogError(err.Error())
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "An unknown error occurred. Please talk to your administrator for help."), nil
+		}
+		if denial != nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, denial.message()), nil
+		}
+	}
+
 	resp, userError, err := handler(stringArgs, args)
 
+	// Only commands that went on to change messages count against the rate
+	// limits. Usage errors, refusals and dry runs are given back.
+	if len(accessOperation) != 0 && (userError || isDryRun(stringArgs)) {
+		p.refundRateLimit(accessOperation, args.UserId, now)
+	}
+
 	if err != nil {
 		p.API.LogError(err.Error())
 		if userError {
@@ -151,36 +284,80 @@ func (p *Plugin) runInfoCommand(args []string, extra *model.CommandArgs) (*model
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, resp), false, nil
 }
 
+// authorizedPluginUser returns if the user may use Wrangler. Users in the
+// denied username or group lists are never authorized. Otherwise, when any of
+// the allowed email domain, username or group lists is configured, the user
+// must match at least one of them.
 func (p *Plugin) authorizedPluginUser(userID string) bool {
 	config := p.getConfiguration()
 
-	if len(config.AllowedEmailDomain) != 0 {
-		user, err := p.API.GetUser(userID)
+	allowedUsernames := parseNameList(config.AllowedUsernames)
+	deniedUsernames := parseNameList(config.DeniedUsernames)
+	allowedGroups := parseNameList(config.AllowedGroups)
+	deniedGroups := parseNameList(config.DeniedGroups)
+
+	restricted := len(config.AllowedEmailDomain) != 0 || len(allowedUsernames) != 0 || len(allowedGroups) != 0
+	if !restricted && len(deniedUsernames) == 0 && len(deniedGroups) == 0 {
+		return true
+	}
+
+	user, err := p.API.GetUser(userID)
+	if err != nil {
+		return false
+	}
+	if containsName(deniedUsernames, user.Username) {
+		return false
+	}
+
+	var groups []*model.Group
+	if len(allowedGroups) != 0 || len(deniedGroups) != 0 {
+		groups, err = p.API.GetGroupsForUser(userID)
 		if err != nil {
+			p.API.LogError("Unable to get groups of user", "user_id", userID, "error", err.Error())
+			return false
+		}
+	}
+	for _, group := range groups {
+		if containsGroup(deniedGroups, group) {
 			return false
 		}
+	}
+
+	if !restricted {
+		return true
+	}
 
+	if len(config.AllowedEmailDomain) != 0 {
 		emailDomains := strings.Split(config.AllowedEmailDomain, ",")
 		for _, emailDomain := range emailDomains {
 			if strings.HasSuffix(user.Email, emailDomain) {
 				return true
 			}
 		}
-
-		return false
+	}
+	if containsName(allowedUsernames, user.Username) {
+		return true
+	}
+	for _, group := range groups {
+		if containsGroup(allowedGroups, group) {
+			return true
+		}
 	}
 
-	return true
+	return false
 }
 
 func getAutocompleteData() *model.AutocompleteData {
-	wrangler := model.NewAutocompleteData("wrangler", "[command]", "Available commands: move, copy, attach, list, info, help")
+	wrangler := model.NewAutocompleteData("wrangler", "[command]", "Available commands: move, copy, merge, split, attach, detach, trace, jobs, undo, history, list, info, help")
 
 	move := model.NewAutocompleteData("move", "[subcommand]", "Move messages")
 	moveThread := model.NewAutocompleteData("thread", "[MESSAGE_ID] [CHANNEL_ID]", "Move a message and the thread it belongs to")
 	moveThread.AddTextArgument("The ID of the message to be moved", "[MESSAGE_ID]", "")
 	moveThread.AddTextArgument("The ID of the channel where the message will be moved to", "[CHANNEL_ID]", "")
 	move.AddCommand(moveThread)
+	moveMessages := model.NewAutocompleteData("messages", "[flags] [CHANNEL_ID]", "Move a range of messages and their threads")
+	moveMessages.AddTextArgument("The range flags followed by the ID of the channel where the messages will be moved to", "[flags] [CHANNEL_ID]", "")
+	move.AddCommand(moveMessages)
 	wrangler.AddCommand(move)
 
 	copy := model.NewAutocompleteData("copy", "[subcommand]", "Copy messages")
@@ -188,8 +365,25 @@ func getAutocompleteData() *model.AutocompleteData {
 	copyThread.AddTextArgument("The ID of the message to be copied", "[MESSAGE_ID]", "")
 	copyThread.AddTextArgument("The ID of the channel where the message will be copied to", "[CHANNEL_ID]", "")
 	copy.AddCommand(copyThread)
+	copyMessages := model.NewAutocompleteData("messages", "[flags] [CHANNEL_ID]", "Copy a range of messages and their threads")
+	copyMessages.AddTextArgument("The range flags followed by the ID of the channel where the messages will be copied to", "[flags] [CHANNEL_ID]", "")
+	copy.AddCommand(copyMessages)
 	wrangler.AddCommand(copy)
 
+	merge := model.NewAutocompleteData("merge", "[subcommand]", "Merge messages")
+	mergeThread := model.NewAutocompleteData("thread", "[SOURCE_ROOT_ID] [TARGET_ROOT_ID]", "Merge a thread into another existing thread")
+	mergeThread.AddTextArgument("The root message ID of the thread to be merged", "[SOURCE_ROOT_ID]", "")
+	mergeThread.AddTextArgument("The root message ID of the thread it will be merged into", "[TARGET_ROOT_ID]", "")
+	merge.AddCommand(mergeThread)
+	wrangler.AddCommand(merge)
+
+	split := model.NewAutocompleteData("split", "[subcommand]", "Split messages")
+	splitThread := model.NewAutocompleteData("thread", "[REPLY_ID] [CHANNEL_ID]", "Split a thread at a given reply into a new thread")
+	splitThread.AddTextArgument("The ID of the reply that will become the new root message", "[REPLY_ID]", "")
+	splitThread.AddTextArgument("The ID of the channel where the new thread will be created", "[CHANNEL_ID]", "")
+	split.AddCommand(splitThread)
+	wrangler.AddCommand(split)
+
 	attach := model.NewAutocompleteData("attach", "[subcommand]", "Attach messages")
 	attachMessage := model.NewAutocompleteData("message", "[MESSAGE_ID_TO_ATTACH] [ROOT_MESSAGE_ID]", "Attach a message to a thread in the channel")
 	attachMessage.AddTextArgument("The ID of the message to be attached", "[MESSAGE_ID_TO_ATTACH]", "")
@@ -197,11 +391,40 @@ func getAutocompleteData() *model.AutocompleteData {
 	attach.AddCommand(attachMessage)
 	wrangler.AddCommand(attach)
 
-	list := model.NewAutocompleteData("list", "[subcommand]", "Lists IDs for channels and messages")
+	detach := model.NewAutocompleteData("detach", "[subcommand]", "Detach messages")
+	detachMessage := model.NewAutocompleteData("message", "[REPLY_ID]", "Detach a reply from its thread into a new message in the channel")
+	detachMessage.AddTextArgument("The ID of the reply to be detached", "[REPLY_ID]", "")
+	detach.AddCommand(detachMessage)
+	wrangler.AddCommand(detach)
+
+	trace := model.NewAutocompleteData("trace", "[MESSAGE_ID]", "Show where a message was wrangled from and to")
+	trace.AddTextArgument("The ID of the message to trace", "[MESSAGE_ID]", "")
+	wrangler.AddCommand(trace)
+
+	jobs := model.NewAutocompleteData("jobs", "[subcommand]", "Manage background jobs")
+	jobsList := model.NewAutocompleteData("list", "", "List your recent background jobs")
+	jobsShow := model.NewAutocompleteData("show", "[JOB_ID]", "Show the status and result of a background job")
+	jobsShow.AddTextArgument("The ID of the job to show", "[JOB_ID]", "")
+	jobsCancel := model.NewAutocompleteData("cancel", "[JOB_ID]", "Cancel a background job that hasn't started yet")
+	jobsCancel.AddTextArgument("The ID of the job to cancel", "[JOB_ID]", "")
+	jobs.AddCommand(jobsList)
+	jobs.AddCommand(jobsShow)
+	jobs.AddCommand(jobsCancel)
+	wrangler.AddCommand(jobs)
+
+	undo := model.NewAutocompleteData("undo", "", "Undo your most recent move, copy or attach")
+	wrangler.AddCommand(undo)
+
+	history := model.NewAutocompleteData("history", "[optional flags]", "Browse the log of wrangler actions")
+	wrangler.AddCommand(history)
+
+	list := model.NewAutocompleteData("list", "[subcommand]", "Lists IDs for channels, messages and threads")
 	listChannels := model.NewAutocompleteData("channels", "[optional flags]", "List channel IDs that you have joined")
 	listMessages := model.NewAutocompleteData("messages", "[optional flags]", "List message IDs in this channel")
+	listThreads := model.NewAutocompleteData("threads", "[optional flags]", "List the most recently active threads in this channel")
 	list.AddCommand(listChannels)
 	list.AddCommand(listMessages)
+	list.AddCommand(listThreads)
 	wrangler.AddCommand(list)
 
 	info := model.NewAutocompleteData("info", "", "Shows plugin information")
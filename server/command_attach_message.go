@@ -5,23 +5,48 @@ import (
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 )
 
-const attachMessageCommand = `Error: missing arguments
+const attachMessageUsage = `/wrangler attach message [MESSAGE_ID_TO_ATTACH] [ROOT_MESSAGE_ID]
+  Attach a given message to a thread in the same channel
+    - Obtain the message IDs by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
+	Flags:
+%s`
 
-/wrangler attach message [MESSAGE_ID_TO_BE_ATTACHED] [ROOT_MESSAGE_ID]
-	Attach a given message to a thread in the same channel
-	  - Obtain the message IDs by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
-`
+func getAttachMessageFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("attach message", pflag.ContinueOnError)
+	flagSet.Bool(flagDryRun, false, "Report what the attach would do without changing anything")
+
+	return flagSet
+}
+
+func parseAttachMessageFlagArgs(args []string) (bool, error) {
+	flagSet := getAttachMessageFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to parse attach message flag args")
+	}
+
+	return flagSet.GetBool(flagDryRun)
+}
+
+func getAttachMessageUsage() string {
+	return fmt.Sprintf(attachMessageUsage, getAttachMessageFlagSet().FlagUsages())
+}
 
 func getAttachMessageCommand() string {
-	return codeBlock(attachMessageCommand)
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getAttachMessageUsage()))
 }
 
 func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
 	if len(args) < 2 {
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getAttachMessageCommand()), true, nil
 	}
+	dryRun, err := parseAttachMessageFlagArgs(args)
+	if err != nil {
+		return nil, true, err
+	}
 	postToBeAttachedID := args[0]
 	postToAttachToID := args[1]
 
@@ -58,6 +83,29 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 	// 4. The command was run from the original channel with the posts, so they
 	//    are also a member of that channel.
 
+	var blockers []moveOrCopyBlocker
+	blocker := p.getOthersPostsBlocker(operationAttach, []*model.Post{postToBeAttached}, extra.ChannelId, extra.UserId)
+	if blocker != nil {
+		blockers = append(blockers, *blocker)
+	}
+	fileBlockers, err := p.getFileAttachmentBlockers([]*model.Post{postToBeAttached})
+	if err != nil {
+		return nil, false, err
+	}
+	blockers = append(blockers, fileBlockers...)
+
+	if dryRun {
+		channel, appErr := p.API.GetChannel(extra.ChannelId)
+		if appErr != nil {
+			return nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
+		}
+
+		return p.runDryRun(operationAttach, newWranglerPostListFromPosts([]*model.Post{postToBeAttached}), channel, blockers, "", extra.UserId)
+	}
+	if len(blockers) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, blockers[0].message), blockers[0].userError, nil
+	}
+
 	currentTeam, appErr := p.API.GetTeam(extra.TeamId)
 	if appErr != nil {
 		return nil, false, errors.Wrap(appErr, "failed to lookup lookup team")
@@ -76,48 +124,45 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 		"new_root_id", newRootID,
 	)
 
-	if len(postToBeAttached.FileIds) != 0 {
-		// TODO: check number of files that need to be re-uploaded or file size?
-		p.API.LogInfo("Wrangler is re-uploading file attachments",
-			"file_count", len(postToBeAttached.FileIds),
-		)
-
-		var newFileIDs []string
-		var fileBytes []byte
-		var oldFileInfo, newFileInfo *model.FileInfo
-		for _, fileID := range postToBeAttached.FileIds {
-			oldFileInfo, appErr = p.API.GetFileInfo(fileID)
-			if appErr != nil {
-				return nil, false, errors.Wrap(appErr, "unable to lookup file info to re-upload")
-			}
-			fileBytes, appErr = p.API.GetFile(fileID)
-			if appErr != nil {
-				return nil, false, errors.Wrap(appErr, "unable to get file bytes to re-upload")
-			}
-			newFileInfo, appErr = p.API.UploadFile(fileBytes, postToBeAttached.ChannelId, oldFileInfo.Name)
-			if appErr != nil {
-				return nil, false, errors.Wrap(appErr, "unable to re-upload file")
-			}
-
-			newFileIDs = append(newFileIDs, newFileInfo.Id)
-		}
+	audit := newAuditEntry(operationAttach, extra.UserId, extra.ChannelId, extra.ChannelId, []*model.Post{postToBeAttached})
+	defer p.recordAuditEntry(audit)
 
-		postToBeAttached.FileIds = newFileIDs
+	journal, err := p.startJournal(operationAttach, extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = p.newFileTransfer(postToBeAttached.ChannelId).run([]*model.Post{postToBeAttached})
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
 	}
 
 	// Store reactions to be reapplied later.
 	reactions, appErr := p.API.GetReactions(postToBeAttached.Id)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "failed to get reactions on original post")
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "failed to get reactions on original post"))
 	}
 
+	provenance := newProvenance(postToBeAttached, extra.TeamId, operationAttach, extra.UserId)
+	provenance.addToPost(postToBeAttached)
+
 	cleanPostID(postToBeAttached)
 	postToBeAttached.RootId = newRootID
 	postToBeAttached.ParentId = newRootID
 
 	newPost, appErr := p.API.CreatePost(postToBeAttached)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "failed to create new post")
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "failed to create new post"))
+	}
+	err = p.journalPost(journal, newPost.Id)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	provenance.PostID = newPost.Id
+	err = p.recordProvenance(journal, provenance)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
 	}
 
 	for _, reaction := range reactions {
@@ -128,10 +173,25 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 		}
 	}
 
+	err = p.commitJournal(journal, []string{cleanupID})
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
 	appErr = p.API.DeletePost(cleanupID)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to delete post")
-	}
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to delete post"))
+	}
+	p.completeJournal(journal)
+	p.saveUndoRecord(&UndoRecord{
+		Operation:         operationAttach,
+		UserID:            extra.UserId,
+		CreateAt:          model.GetMillis(),
+		OriginalChannelID: extra.ChannelId,
+		TargetChannelID:   extra.ChannelId,
+		NewRootPostID:     newPost.Id,
+		NewPostIDs:        []string{newPost.Id},
+	})
 
 	p.API.LogInfo("Wrangler has attached a message",
 		"user_id", extra.UserId,
@@ -153,6 +213,8 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 
 	msg := fmt.Sprintf("Message successfully attached to thread")
 
+	audit.succeeded()
+
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
 }
 
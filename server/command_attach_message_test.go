@@ -76,9 +76,15 @@ func TestAttachMessageCommand(t *testing.T) {
 	api.On("DeletePost", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
 	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(directChannel, nil)
 	api.On("GetReactions", mock.AnythingOfType("string")).Return(reactions, nil)
+	api.On("GetChannel", channel1.Id).Return(channel1, nil)
+	api.On("GetChannelMember", channel1.Id, mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
 	api.On("AddReaction", mock.Anything).Return(nil, nil)
 	api.On("GetTeam", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(currentTeam, nil)
 	api.On("GetConfig", mock.Anything).Return(config)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
 	api.On("LogInfo",
 		mock.AnythingOfTypeArgument("string"),
 		mock.AnythingOfTypeArgument("string"),
@@ -175,6 +181,37 @@ func TestAttachMessageCommand(t *testing.T) {
 		assert.Contains(t, resp.Text, "Error: the message to be attached is already part of a thread")
 	})
 
+	t.Run("dry run", func(t *testing.T) {
+		createPostCalls := countMockCalls(&api.Mock, "CreatePost")
+
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id, "--dry-run"}, &model.CommandArgs{ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Dry run: this is what the attach would do")
+		assert.Equal(t, createPostCalls, countMockCalls(&api.Mock, "CreatePost"))
+	})
+
+	t.Run("message written by another user", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{MoveOthersPostsRequirePermission: true})
+		userID := model.NewId()
+		api.On("HasPermissionToChannel", userID, channel1.Id, mock.Anything).Return(false)
+
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{UserId: userID, ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: Wrangler is configured to only let you attach messages that you wrote")
+
+		resp, isUserError, err = plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id, "--dry-run"}, &model.CommandArgs{UserId: userID, ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "only let you attach messages that you wrote")
+
+		resp, isUserError, err = plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id, "--dry-run"}, &model.CommandArgs{UserId: postToBeAttached.UserId, ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.NotContains(t, resp.Text, "only let you attach messages that you wrote")
+	})
+
 	t.Run("attach message successfully", func(t *testing.T) {
 		plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: true})
 		require.NoError(t, plugin.configuration.IsValid())
@@ -5,22 +5,88 @@ import (
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 )
 
 const copyThreadUsage = `/wrangler copy thread [MESSAGE_ID] [CHANNEL_ID]
   Copy a given message, along with the thread it belongs to, to a given channel
     - This can be on any channel in any team that you have joined
     - Obtain the message ID by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
-    - Obtain the channel ID by running '/wrangler list channels' or via the channel 'View Info' option`
+    - Obtain the channel ID by running '/wrangler list channels' or via the channel 'View Info' option
+	Flags:
+%s`
+
+type copyThreadOptions struct {
+	preserveTimestamps bool
+	silent             bool
+	dryRun             bool
+	participants       string
+}
+
+func getCopyThreadFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("copy thread", pflag.ContinueOnError)
+	flagSet.Bool(flagPreserveTimestamps, false, "Keep the original timestamps of the copied messages (defaults to the plugin configuration)")
+	flagSet.Bool(flagSilent, true, "Don't notify users who are mentioned in the copied messages again by adding a zero-width space after each @ in the stored text")
+	flagSet.Bool(flagDryRun, false, "Report what the copy would do without changing anything")
+	addParticipantsFlag(flagSet)
+
+	return flagSet
+}
+
+func parseCopyThreadFlagArgs(args []string, preserveTimestampsDefault bool) (copyThreadOptions, error) {
+	var options copyThreadOptions
+
+	flagSet := getCopyThreadFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, errors.Wrap(err, "unable to parse copy thread flag args")
+	}
+
+	options.preserveTimestamps, err = getBoolFlagWithDefault(flagSet, flagPreserveTimestamps, preserveTimestampsDefault)
+	if err != nil {
+		return options, err
+	}
+
+	options.silent, err = flagSet.GetBool(flagSilent)
+	if err != nil {
+		return options, err
+	}
+
+	options.dryRun, err = flagSet.GetBool(flagDryRun)
+	if err != nil {
+		return options, err
+	}
+
+	options.participants, err = getParticipantsFlag(flagSet)
+	if err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+func getCopyThreadUsage() string {
+	return fmt.Sprintf(copyThreadUsage, getCopyThreadFlagSet().FlagUsages())
+}
 
 func getCopyThreadMessage() string {
-	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", copyThreadUsage))
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getCopyThreadUsage()))
 }
 
 func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	return p.runCopyThread(args, extra, nil)
+}
+
+// runCopyThread copies a thread. The job is the background job running the
+// command, or nil if the command wasn't queued.
+func (p *Plugin) runCopyThread(args []string, extra *model.CommandArgs, job *WranglerJob) (*model.CommandResponse, bool, error) {
 	if len(args) < 2 {
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getCopyThreadMessage()), true, nil
 	}
+	options, err := parseCopyThreadFlagArgs(args, p.getConfiguration().PreserveTimestampsByDefault)
+	if err != nil {
+		return nil, true, err
+	}
 	postID := args[0]
 	channelID := args[1]
 
@@ -43,11 +109,31 @@ func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (
 		return nil, false, fmt.Errorf("unable to get channel with ID %s", channelID)
 	}
 
-	response, userErr, err := p.validateMoveOrCopy(wpl, originalChannel, targetChannel, extra)
+	if options.dryRun {
+		blockers, err := p.getMoveOrCopyBlockers(operationCopy, wpl, originalChannel, targetChannel, extra)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return p.runDryRun(operationCopy, wpl, targetChannel, blockers, options.participants, extra.UserId)
+	}
+
+	response, userErr, err := p.validateMoveOrCopy(operationCopy, wpl, originalChannel, targetChannel, extra)
 	if response != nil || err != nil {
 		return response, userErr, err
 	}
 
+	response, err = p.queueLargeJob(jobCommandCopyThread, job, wpl.NumPosts(), args, extra)
+	if response != nil || err != nil {
+		return response, false, err
+	}
+
+	nonMemberIDs, response, err := p.prepareNonMemberParticipants(options.participants, []*WranglerPostList{wpl}, targetChannel, extra.UserId)
+	if response != nil || err != nil {
+		// The participants option refusing the operation is a user error.
+		return response, response != nil, err
+	}
+
 	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
 	if appErr != nil {
 		return nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
@@ -59,12 +145,26 @@ func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (
 		"original_channel_id", originalChannel.Id,
 	)
 
-	newRootPost, err := p.copyWranglerPostlist(wpl, targetChannel)
+	audit := newAuditEntry(operationCopy, extra.UserId, originalChannel.Id, targetChannel.Id, wpl.Posts)
+	defer p.recordAuditEntry(audit)
+
+	journal, err := p.startJournal(operationCopy, extra.UserId)
 	if err != nil {
 		return nil, false, err
 	}
 
-	_, appErr = p.API.CreatePost(&model.Post{
+	newRootPost, err := p.copyWranglerPostlist(wpl, targetChannel, copyOptions{
+		preserveTimestamps: options.preserveTimestamps,
+		silent:             options.silent,
+		operation:          operationCopy,
+		userID:             extra.UserId,
+		originalTeamID:     originalChannel.TeamId,
+	}, journal)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	botPost, appErr := p.API.CreatePost(&model.Post{
 		UserId:    p.BotUserID,
 		RootId:    newRootPost.Id,
 		ParentId:  newRootPost.Id,
@@ -72,11 +172,15 @@ func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (
 		Message:   "This thread was copied from another channel",
 	})
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to create new bot post")
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to create new bot post"))
+	}
+	err = p.journalPost(journal, botPost.Id)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
 	}
 
 	newPostLink := makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, newRootPost.Id)
-	_, appErr = p.API.CreatePost(&model.Post{
+	botPost, appErr = p.API.CreatePost(&model.Post{
 		UserId:    p.BotUserID,
 		RootId:    wpl.RootPost().Id,
 		ParentId:  wpl.RootPost().Id,
@@ -84,8 +188,14 @@ func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (
 		Message:   fmt.Sprintf("A copy of this thread has been made: %s", newPostLink),
 	})
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to create new bot post")
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to create new bot post"))
 	}
+	err = p.journalPost(journal, botPost.Id)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+	p.completeJournal(journal)
+	p.saveUndoRecord(newThreadUndoRecord(operationCopy, extra.UserId, originalChannel, targetChannel, wpl, journal))
 
 	p.API.LogInfo("Wrangler thread copy complete",
 		"user_id", extra.UserId,
@@ -104,6 +214,9 @@ func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (
 			)
 		}
 	}
+	p.finishNonMemberParticipants(options.participants, nonMemberIDs, targetChannel, newPostLink)
+
+	audit.succeeded()
 
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Thread copy complete"), false, nil
 }
@@ -73,6 +73,7 @@ func TestCopyThreadCommand(t *testing.T) {
 	api.On("GetChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(targetChannel, nil)
 	api.On("GetPostThread", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(generatedPosts, nil)
 	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(mockGenerateChannelMember(), nil)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
 	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(directChannel, nil)
 	api.On("GetTeam", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(targetTeam, nil)
 	api.On("CreatePost", mock.Anything, mock.Anything).Return(mockGeneratePost(), nil)
@@ -80,6 +81,10 @@ func TestCopyThreadCommand(t *testing.T) {
 	api.On("GetReactions", mock.AnythingOfType("string")).Return(reactions, nil)
 	api.On("AddReaction", mock.Anything).Return(nil, nil)
 	api.On("GetConfig", mock.Anything).Return(config)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
 	api.On("LogInfo",
 		mock.AnythingOfTypeArgument("string"),
 		mock.AnythingOfTypeArgument("string"),
@@ -194,6 +199,19 @@ func TestCopyThreadCommand(t *testing.T) {
 		assert.Contains(t, resp.Text, "Thread copy complete")
 	})
 
+	t.Run("dry run", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: true})
+		require.NoError(t, plugin.configuration.IsValid())
+		createPostCalls := countMockCalls(&api.Mock, "CreatePost")
+
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2", "--dry-run"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Dry run: this is what the copy would do")
+		assert.Contains(t, resp.Text, "No rules would block the operation")
+		assert.Equal(t, createPostCalls, countMockCalls(&api.Mock, "CreatePost"))
+	})
+
 	t.Run("thread is above configuration move-maximum", func(t *testing.T) {
 		plugin.setConfiguration(&configuration{MoveThreadMaxCount: "1"})
 		require.NoError(t, plugin.configuration.IsValid())
@@ -203,3 +221,79 @@ func TestCopyThreadCommand(t *testing.T) {
 		assert.Contains(t, resp.Text, "Error: the thread is 3 posts long, but this command is configured to only move threads of up to 1 posts")
 	})
 }
+
+func TestCopyWranglerPostlistTimestamps(t *testing.T) {
+	var createdPosts []*model.Post
+	api := &plugintest.API{}
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil).Run(func(args mock.Arguments) {
+		createdPosts = append(createdPosts, args.Get(0).(*model.Post))
+	})
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	wpl := buildWranglerPostList(mockGenerateThread(3, model.NewId()))
+	wpl.Posts[1].EditAt = wpl.Posts[1].CreateAt + 10
+	targetChannel := &model.Channel{Id: model.NewId()}
+	journal := &WranglerJournal{ID: model.NewId()}
+
+	t.Run("new timestamps", func(t *testing.T) {
+		createdPosts = nil
+		_, err := plugin.copyWranglerPostlist(wpl, targetChannel, copyOptions{}, journal)
+		require.NoError(t, err)
+		require.Len(t, createdPosts, 3)
+		for _, post := range createdPosts {
+			assert.Zero(t, post.CreateAt)
+			assert.Zero(t, post.EditAt)
+		}
+	})
+
+	t.Run("preserved timestamps", func(t *testing.T) {
+		createdPosts = nil
+		_, err := plugin.copyWranglerPostlist(wpl, targetChannel, copyOptions{preserveTimestamps: true}, journal)
+		require.NoError(t, err)
+		require.Len(t, createdPosts, 3)
+		for i, post := range createdPosts {
+			assert.Equal(t, wpl.Posts[i].CreateAt, post.CreateAt)
+			assert.Equal(t, wpl.Posts[i].EditAt, post.EditAt)
+		}
+	})
+
+	t.Run("silent", func(t *testing.T) {
+		message := wpl.Posts[2].Message
+		wpl.Posts[2].Message = "@channel please review"
+		defer func() { wpl.Posts[2].Message = message }()
+
+		createdPosts = nil
+		_, err := plugin.copyWranglerPostlist(wpl, targetChannel, copyOptions{silent: true}, journal)
+		require.NoError(t, err)
+		require.Len(t, createdPosts, 3)
+		assert.Equal(t, "@\u200bchannel please review", createdPosts[2].Message)
+		assert.Equal(t, "@channel please review", wpl.Posts[2].Message)
+
+		createdPosts = nil
+		_, err = plugin.copyWranglerPostlist(wpl, targetChannel, copyOptions{}, journal)
+		require.NoError(t, err)
+		require.Len(t, createdPosts, 3)
+		assert.Equal(t, "@channel please review", createdPosts[2].Message)
+	})
+
+	t.Run("pinned", func(t *testing.T) {
+		wpl := buildWranglerPostList(mockGenerateThread(3, model.NewId()))
+		wpl.Posts[1].IsPinned = true
+		wpl = newWranglerPostListFromPosts(wpl.Posts)
+		assert.Equal(t, 1, wpl.PinnedPostCount)
+
+		createdPosts = nil
+		_, err := plugin.copyWranglerPostlist(wpl, targetChannel, copyOptions{}, journal)
+		require.NoError(t, err)
+		require.Len(t, createdPosts, 3)
+		assert.False(t, createdPosts[0].IsPinned)
+		assert.True(t, createdPosts[1].IsPinned)
+		assert.False(t, createdPosts[2].IsPinned)
+	})
+}
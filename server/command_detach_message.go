@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	detachMessageUsage = `/wrangler detach message [REPLY_ID]
+  Detach a given reply from its thread, turning it into a new message in the same channel
+    - Obtain the message ID via the 'Permalink' message dropdown option (it's the last part of the URL)
+	Flags:
+%s`
+
+	flagDetachMessageQuoteRoot = "quote-root-message"
+)
+
+func getDetachMessageFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("detach message", pflag.ContinueOnError)
+	flagSet.Bool(flagDetachMessageQuoteRoot, false, "Quote the original thread root message in the detached message for context")
+
+	return flagSet
+}
+
+func parseDetachMessageFlagArgs(args []string) (bool, error) {
+	flagSet := getDetachMessageFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to parse detach message flag args")
+	}
+
+	return flagSet.GetBool(flagDetachMessageQuoteRoot)
+}
+
+func getDetachMessageUsage() string {
+	return fmt.Sprintf(detachMessageUsage, getDetachMessageFlagSet().FlagUsages())
+}
+
+func getDetachMessageMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getDetachMessageUsage()))
+}
+
+func (p *Plugin) runDetachMessageCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getDetachMessageMessage()), true, nil
+	}
+	quoteRootMessage, err := parseDetachMessageFlagArgs(args)
+	if err != nil {
+		return nil, true, err
+	}
+	postID := args[0]
+
+	postToBeDetached, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postID)), true, nil
+	}
+	if postToBeDetached.ChannelId != extra.ChannelId {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the detach command must be run from the channel containing the message"), true, nil
+	}
+	if len(postToBeDetached.RootId) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the message to be detached is not part of a thread"), true, nil
+	}
+
+	blocker := p.getOthersPostsBlocker(operationDetach, []*model.Post{postToBeDetached}, extra.ChannelId, extra.UserId)
+	if blocker != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, blocker.message), blocker.userError, nil
+	}
+
+	var rootPost *model.Post
+	if quoteRootMessage {
+		rootPost, appErr = p.API.GetPost(postToBeDetached.RootId)
+		if appErr != nil {
+			return nil, false, errors.Wrap(appErr, "unable to get thread root message")
+		}
+	}
+
+	currentTeam, appErr := p.API.GetTeam(extra.TeamId)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "failed to lookup team")
+	}
+
+	p.API.LogInfo("Wrangler is detaching a message",
+		"user_id", extra.UserId,
+		"post_to_be_detached", postToBeDetached.Id,
+		"original_root_id", postToBeDetached.RootId,
+	)
+
+	audit := newAuditEntry(operationDetach, extra.UserId, extra.ChannelId, extra.ChannelId, []*model.Post{postToBeDetached})
+	defer p.recordAuditEntry(audit)
+
+	newPost, err := p.detachPost(postToBeDetached, rootPost, operationDetach, extra.UserId, extra.TeamId)
+	if err != nil {
+		return nil, false, err
+	}
+	p.clearUndoRecord(extra.UserId)
+	audit.succeeded()
+
+	p.API.LogInfo("Wrangler has detached a message",
+		"user_id", extra.UserId,
+		"post_to_be_detached", postToBeDetached.Id,
+		"new_post_id", newPost.Id,
+	)
+
+	if extra.UserId != postToBeDetached.UserId {
+		// The wrangled message was not created by the user running the command.
+		// Send a DM to the user who created it to let them know.
+		err := p.postDetachMessageBotDM(postToBeDetached.UserId, makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, currentTeam.Name, newPost.Id))
+		if err != nil {
+			p.API.LogError("Unable to send detach-message DM to user",
+				"error", err.Error(),
+				"user_id", postToBeDetached.UserId,
+			)
+		}
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Message successfully detached from thread"), false, nil
+}
+
+// detachPost recreates a reply as a new top-level message in the same channel
+// and deletes the original reply. File attachments and reactions are kept.
+// When a root post is provided, it is quoted at the start of the new message.
+// The operation is recorded in the journal and the new message's provenance.
+func (p *Plugin) detachPost(post *model.Post, rootPost *model.Post, operation, userID, teamID string) (*model.Post, error) {
+	journal, err := p.startJournal(operation, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	newPost := post.Clone()
+	cleanPostID(newPost)
+	newPost.RootId = ""
+	newPost.ParentId = ""
+	if rootPost != nil {
+		newPost.Message = fmt.Sprintf("%s\n\n%s",
+			quoteBlock(fmt.Sprintf("Originally a reply to: %s", cleanAndTrimMessage(rootPost.Message, 200))),
+			post.Message,
+		)
+	}
+
+	err = p.newFileTransfer(post.ChannelId).run([]*model.Post{newPost})
+	if err != nil {
+		return nil, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	reactions := p.getReactionsToCopy(post.Id)
+
+	provenance := newProvenance(post, teamID, operation, userID)
+	provenance.addToPost(newPost)
+
+	newPost, appErr := p.API.CreatePost(newPost)
+	if appErr != nil {
+		return nil, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "failed to create new post"))
+	}
+	err = p.journalPost(journal, newPost.Id)
+	if err != nil {
+		return nil, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	provenance.PostID = newPost.Id
+	err = p.recordProvenance(journal, provenance)
+	if err != nil {
+		return nil, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	p.copyReactions(reactions, newPost.Id)
+
+	err = p.cleanupOriginalPosts(newWranglerPostListFromPosts([]*model.Post{post}), journal)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPost, nil
+}
+
+func (p *Plugin) postDetachMessageBotDM(userID, newPostLink string) error {
+	return p.PostBotDM(userID, fmt.Sprintf(
+		"Someone wrangled one of your messages out of a thread for you: %s", newPostLink,
+	))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetachMessageCommand(t *testing.T) {
+	team1 := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	channel1 := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+		Name:   "channel1",
+		Type:   model.CHANNEL_OPEN,
+	}
+	rootPost := &model.Post{
+		Id:        model.NewId(),
+		UserId:    model.NewId(),
+		ChannelId: channel1.Id,
+		Message:   "the root message",
+	}
+	reply := &model.Post{
+		Id:        model.NewId(),
+		UserId:    model.NewId(),
+		ChannelId: channel1.Id,
+		RootId:    rootPost.Id,
+		ParentId:  rootPost.Id,
+		Message:   "the reply",
+	}
+	replyInAnotherChannel := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: model.NewId(),
+		RootId:    model.NewId(),
+	}
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	var createdPost *model.Post
+	api := &plugintest.API{}
+	api.On("GetPost", rootPost.Id).Return(rootPost, nil)
+	api.On("GetPost", reply.Id).Return(reply, nil)
+	api.On("GetPost", replyInAnotherChannel.Id).Return(replyInAnotherChannel, nil)
+	api.On("GetPost", mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetPost", "not.found", nil, "", 404))
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil).Run(func(args mock.Arguments) {
+		createdPost = args.Get(0).(*model.Post)
+	})
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.Channel{Id: model.NewId()}, nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	api.On("GetTeam", mock.AnythingOfType("string")).Return(team1, nil)
+	api.On("GetConfig").Return(config)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{}, &model.CommandArgs{ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{model.NewId()}, &model.CommandArgs{ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to get message with ID")
+	})
+
+	t.Run("not in channel with message", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{replyInAnotherChannel.Id}, &model.CommandArgs{ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the detach command must be run from the channel containing the message")
+	})
+
+	t.Run("message is not a reply", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{rootPost.Id}, &model.CommandArgs{ChannelId: channel1.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the message to be detached is not part of a thread")
+	})
+
+	t.Run("detach message successfully", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{reply.Id}, &model.CommandArgs{ChannelId: channel1.Id, UserId: reply.UserId})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Message successfully detached from thread")
+		require.NotNil(t, createdPost)
+		assert.Empty(t, createdPost.RootId)
+		assert.Equal(t, "the reply", createdPost.Message)
+		api.AssertCalled(t, "DeletePost", reply.Id)
+	})
+
+	t.Run("detach message with root quote", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{reply.Id, "--quote-root-message"}, &model.CommandArgs{ChannelId: channel1.Id, UserId: reply.UserId})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Message successfully detached from thread")
+		require.NotNil(t, createdPost)
+		assert.Contains(t, createdPost.Message, quoteBlock("Originally a reply to: the root message"))
+		assert.Contains(t, createdPost.Message, "the reply")
+	})
+
+	t.Run("message written by another user", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{MoveOthersPostsRequirePermission: true})
+		defer plugin.setConfiguration(&configuration{})
+		userID := model.NewId()
+		api.On("HasPermissionToChannel", userID, channel1.Id, mock.Anything).Return(false)
+		createPostCalls := countMockCalls(&api.Mock, "CreatePost")
+
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{reply.Id}, &model.CommandArgs{ChannelId: channel1.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: Wrangler is configured to only let you detach messages that you wrote")
+		assert.Equal(t, createPostCalls, countMockCalls(&api.Mock, "CreatePost"))
+
+		resp, isUserError, err = plugin.runDetachMessageCommand([]string{reply.Id}, &model.CommandArgs{ChannelId: channel1.Id, UserId: reply.UserId})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Message successfully detached from thread")
+	})
+}
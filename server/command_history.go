@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagHistoryUser    = "user"
+	flagHistoryChannel = "channel"
+	flagHistorySince   = "since"
+	flagHistoryCount   = "count"
+	minHistoryCount    = 1
+	maxHistoryCount    = 100
+)
+
+type historyOptions struct {
+	username  string
+	channelID string
+	since     int64
+	count     int
+}
+
+func getHistoryFlagSet() *pflag.FlagSet {
+	historyFlagSet := pflag.NewFlagSet("history", pflag.ContinueOnError)
+	historyFlagSet.String(flagHistoryUser, "", "Only show actions run by this user. Only system admins can view the actions of other users")
+	historyFlagSet.String(flagHistoryChannel, "", "Only show actions that wrangled messages from or to the channel with this ID")
+	historyFlagSet.String(flagHistorySince, "", "Only show actions run after this time; an RFC 3339 timestamp or a duration before now, such as 24h")
+	historyFlagSet.Int(flagHistoryCount, 20, fmt.Sprintf("Number of actions to return. Must be between %d and %d", minHistoryCount, maxHistoryCount))
+
+	return historyFlagSet
+}
+
+func parseHistoryArgs(args []string, now time.Time) (historyOptions, error) {
+	var options historyOptions
+
+	historyFlagSet := getHistoryFlagSet()
+	err := historyFlagSet.Parse(args)
+	if err != nil {
+		return options, err
+	}
+
+	options.username, err = historyFlagSet.GetString(flagHistoryUser)
+	if err != nil {
+		return options, err
+	}
+	options.username = strings.TrimPrefix(options.username, "@")
+
+	options.channelID, err = historyFlagSet.GetString(flagHistoryChannel)
+	if err != nil {
+		return options, err
+	}
+
+	since, err := historyFlagSet.GetString(flagHistorySince)
+	if err != nil {
+		return options, err
+	}
+	if len(since) != 0 {
+		options.since, err = parseTimeFlag(since, now)
+		if err != nil {
+			return options, fmt.Errorf("%s: %s", flagHistorySince, err)
+		}
+	}
+
+	options.count, err = historyFlagSet.GetInt(flagHistoryCount)
+	if err != nil {
+		return options, err
+	}
+	if options.count < minHistoryCount || options.count > maxHistoryCount {
+		return options, fmt.Errorf("%s (%d) must be between %d and %d", flagHistoryCount, options.count, minHistoryCount, maxHistoryCount)
+	}
+
+	return options, nil
+}
+
+func (p *Plugin) runHistoryCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	options, err := parseHistoryArgs(args, time.Now())
+	if err != nil {
+		return nil, true, err
+	}
+
+	filter := &auditLogFilter{
+		ChannelID: options.channelID,
+		Since:     options.since,
+	}
+
+	isAdmin := p.API.HasPermissionTo(extra.UserId, model.PERMISSION_MANAGE_SYSTEM)
+	if !isAdmin {
+		filter.UserID = extra.UserId
+	}
+	if len(options.username) != 0 {
+		user, appErr := p.API.GetUserByUsername(options.username)
+		if appErr != nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find user @%s", options.username)), true, nil
+		}
+		if !isAdmin && user.Id != extra.UserId {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: only system admins can view the actions of other users"), true, nil
+		}
+		filter.UserID = user.Id
+	}
+
+	var entries []*AuditEntry
+	err = p.forEachAuditEntry(filter, func(entry *AuditEntry) bool {
+		entries = append(entries, entry)
+		return len(entries) < options.count
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(entries) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No wrangler actions were found"), false, nil
+	}
+
+	usernames := make(map[string]string)
+	channelNames := make(map[string]string)
+	msg := "Recent wrangler actions\n\n| Time | User | Operation | From | To | Messages | Outcome |\n| -- | -- | -- | -- | -- | -- | -- |\n"
+	for _, entry := range entries {
+		msg += fmt.Sprintf("| %s | @%s | %s | %s | %s | %d | %s |\n",
+			formatTimestamp(entry.CreateAt),
+			p.getCachedUsername(entry.UserID, usernames),
+			entry.Operation,
+			p.getCachedChannelName(entry.SourceChannelID, channelNames),
+			p.getCachedChannelName(entry.TargetChannelID, channelNames),
+			entry.PostCount,
+			entry.Outcome,
+		)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+func (p *Plugin) getCachedChannelName(channelID string, channelNames map[string]string) string {
+	if len(channelID) == 0 {
+		return ""
+	}
+	if name, ok := channelNames[channelID]; ok {
+		return name
+	}
+
+	name := channelID
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr == nil && len(channel.Name) != 0 {
+		name = "~" + channel.Name
+	}
+	channelNames[channelID] = name
+
+	return name
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryCommand(t *testing.T) {
+	owner := &model.User{Id: model.NewId(), Username: "owner"}
+	other := &model.User{Id: model.NewId(), Username: "other"}
+	adminID := model.NewId()
+	sourceChannel := &model.Channel{Id: model.NewId(), Name: "source"}
+	targetChannel := &model.Channel{Id: model.NewId(), Name: "target"}
+
+	api := &plugintest.API{}
+	mockKVStore(api)
+	api.On("HasPermissionTo", adminID, model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", mock.AnythingOfType("string"), model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	api.On("GetUser", owner.Id).Return(owner, nil)
+	api.On("GetUser", other.Id).Return(other, nil)
+	api.On("GetUserByUsername", owner.Username).Return(owner, nil)
+	api.On("GetUserByUsername", other.Username).Return(other, nil)
+	api.On("GetUserByUsername", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
+	api.On("GetChannel", sourceChannel.Id).Return(sourceChannel, nil)
+	api.On("GetChannel", targetChannel.Id).Return(targetChannel, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	ownerEntry := newAuditEntry(operationMove, owner.Id, sourceChannel.Id, targetChannel.Id, []*model.Post{{Id: model.NewId()}, {Id: model.NewId()}})
+	ownerEntry.succeeded()
+	plugin.recordAuditEntry(ownerEntry)
+	oldEntry := newAuditEntry(operationCopy, owner.Id, sourceChannel.Id, targetChannel.Id, nil)
+	oldEntry.CreateAt = model.GetMillisForTime(time.Now().Add(-48 * time.Hour))
+	plugin.recordAuditEntry(oldEntry)
+	otherEntry := newAuditEntry(operationSplit, other.Id, targetChannel.Id, targetChannel.Id, []*model.Post{{Id: model.NewId()}})
+	plugin.recordAuditEntry(otherEntry)
+
+	t.Run("invalid count", func(t *testing.T) {
+		_, isUserError, err := plugin.runHistoryCommand([]string{"--count", "0"}, &model.CommandArgs{UserId: owner.Id})
+		require.Error(t, err)
+		assert.True(t, isUserError)
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		_, isUserError, err := plugin.runHistoryCommand([]string{"--since", "yesterday"}, &model.CommandArgs{UserId: owner.Id})
+		require.Error(t, err)
+		assert.True(t, isUserError)
+	})
+
+	t.Run("own actions", func(t *testing.T) {
+		resp, isUserError, err := plugin.runHistoryCommand([]string{}, &model.CommandArgs{UserId: owner.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "| @owner | move | ~source | ~target | 2 | succeeded |")
+		assert.Contains(t, resp.Text, "| @owner | copy | ~source | ~target | 0 | failed |")
+		assert.NotContains(t, resp.Text, "@other")
+	})
+
+	t.Run("since", func(t *testing.T) {
+		resp, _, err := plugin.runHistoryCommand([]string{"--since", "24h"}, &model.CommandArgs{UserId: owner.Id})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Text, "| move |")
+		assert.NotContains(t, resp.Text, "| copy |")
+	})
+
+	t.Run("actions of another user", func(t *testing.T) {
+		resp, isUserError, err := plugin.runHistoryCommand([]string{"--user", "@other"}, &model.CommandArgs{UserId: owner.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: only system admins can view the actions of other users")
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		resp, isUserError, err := plugin.runHistoryCommand([]string{"--user", "nobody"}, &model.CommandArgs{UserId: adminID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to find user @nobody")
+	})
+
+	t.Run("no actions", func(t *testing.T) {
+		resp, isUserError, err := plugin.runHistoryCommand([]string{}, &model.CommandArgs{UserId: model.NewId()})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Equal(t, "No wrangler actions were found", resp.Text)
+	})
+
+	t.Run("admins see every action", func(t *testing.T) {
+		resp, _, err := plugin.runHistoryCommand([]string{}, &model.CommandArgs{UserId: adminID})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Text, "@owner")
+		assert.Contains(t, resp.Text, "| @other | split | ~target | ~target | 1 | failed |")
+	})
+
+	t.Run("admins filter by user and channel", func(t *testing.T) {
+		resp, _, err := plugin.runHistoryCommand([]string{"--user", "other", "--channel", sourceChannel.Id}, &model.CommandArgs{UserId: adminID})
+		require.NoError(t, err)
+		assert.Equal(t, "No wrangler actions were found", resp.Text)
+
+		resp, _, err = plugin.runHistoryCommand([]string{"--user", "owner", "--channel", sourceChannel.Id, "--count", "1"}, &model.CommandArgs{UserId: adminID})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Text, "| move |")
+		assert.NotContains(t, resp.Text, "| copy |")
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	jobsUsage = `/wrangler jobs [list|show|cancel] [JOB_ID]
+  Manage the background jobs that large moves and copies are queued as
+    - list: list your recent jobs
+    - show [JOB_ID]: show the status and result of a job
+    - cancel [JOB_ID]: cancel a job that hasn't started yet
+    - System admins can see and cancel the jobs of every user`
+
+	// maxJobsListed limits how many jobs are shown by the list subcommand.
+	maxJobsListed = 20
+)
+
+func getJobsMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", jobsUsage))
+}
+
+func (p *Plugin) runJobsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getJobsMessage()), true, nil
+	}
+
+	switch args[0] {
+	case "list":
+		return p.runJobsListCommand(extra)
+	case "show", "cancel":
+		if len(args) < 2 {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getJobsMessage()), true, nil
+		}
+		if args[0] == "show" {
+			return p.runJobsShowCommand(args[1], extra)
+		}
+		return p.runJobsCancelCommand(args[1], extra)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getJobsMessage()), true, nil
+}
+
+func (p *Plugin) runJobsListCommand(extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	jobs, err := p.listJobs()
+	if err != nil {
+		return nil, false, err
+	}
+
+	isAdmin := p.API.HasPermissionTo(extra.UserId, model.PERMISSION_MANAGE_SYSTEM)
+	var visibleJobs []*WranglerJob
+	for i := len(jobs) - 1; i >= 0 && len(visibleJobs) < maxJobsListed; i-- {
+		if isAdmin || jobs[i].UserID == extra.UserId {
+			visibleJobs = append(visibleJobs, jobs[i])
+		}
+	}
+
+	if len(visibleJobs) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No background jobs were found"), false, nil
+	}
+
+	usernames := make(map[string]string)
+	msg := "Recent background jobs\n\n| Job ID | Command | User | Status | Queued |\n| -- | -- | -- | -- | -- |\n"
+	for _, job := range visibleJobs {
+		msg += fmt.Sprintf("| %s | %s | @%s | %s | %s |\n",
+			inlineCode(job.ID),
+			job.Command,
+			p.getCachedUsername(job.UserID, usernames),
+			job.Status,
+			formatTimestamp(job.CreateAt),
+		)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+func (p *Plugin) runJobsShowCommand(jobID string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	job, err := p.getJob(jobID)
+	if err != nil {
+		return nil, false, err
+	}
+	if job == nil || !p.canAccessJob(job, extra.UserId) {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find job with ID %s", jobID)), true, nil
+	}
+
+	msg := fmt.Sprintf("Background job %s\n\n", inlineCode(job.ID))
+	msg += fmt.Sprintf("- Command: `/wrangler %s %s`\n", job.Command, strings.Join(job.Args, " "))
+	msg += fmt.Sprintf("- Status: %s\n", job.Status)
+	msg += fmt.Sprintf("- Attempts: %d\n", job.Attempts)
+	msg += fmt.Sprintf("- Queued: %s\n", formatTimestamp(job.CreateAt))
+	if job.FinishAt != 0 {
+		msg += fmt.Sprintf("- Done: %s\n", formatTimestamp(job.FinishAt))
+	}
+	if len(job.Error) != 0 {
+		msg += fmt.Sprintf("- Error: %s\n", job.Error)
+	}
+	if len(job.Result) != 0 {
+		msg += fmt.Sprintf("\n%s\n", job.Result)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+func (p *Plugin) runJobsCancelCommand(jobID string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	job, err := p.getJob(jobID)
+	if err != nil {
+		return nil, false, err
+	}
+	if job == nil || !p.canAccessJob(job, extra.UserId) {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find job with ID %s", jobID)), true, nil
+	}
+
+	job, canceled, err := p.cancelJob(jobID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !canceled {
+		status := "removed"
+		if job != nil {
+			status = job.Status
+		}
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: job %s is %s; only pending jobs can be canceled", jobID, status)), true, nil
+	}
+
+	p.API.LogInfo("Wrangler background job canceled",
+		"job_id", job.ID,
+		"user_id", extra.UserId,
+		"command", job.Command,
+	)
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Job %s has been canceled", inlineCode(job.ID))), false, nil
+}
+
+// canAccessJob returns if the user queued the job or is a system admin.
+func (p *Plugin) canAccessJob(job *WranglerJob, userID string) bool {
+	return job.UserID == userID || p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM)
+}
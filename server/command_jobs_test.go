@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobsCommand(t *testing.T) {
+	ownerID := model.NewId()
+	otherUserID := model.NewId()
+	adminID := model.NewId()
+
+	api := setupJobsAPI()
+	api.On("HasPermissionTo", adminID, model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", mock.AnythingOfType("string"), model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	api.On("GetUser", ownerID).Return(&model.User{Id: ownerID, Username: "owner"}, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	job, err := plugin.enqueueJob(jobCommandMoveThread, []string{"post1", "channel1"}, &model.CommandArgs{UserId: ownerID, ChannelId: model.NewId()})
+	require.NoError(t, err)
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{}, &model.CommandArgs{UserId: ownerID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("list own jobs", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"list"}, &model.CommandArgs{UserId: ownerID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, job.ID)
+		assert.Contains(t, resp.Text, "| move thread | @owner | pending |")
+	})
+
+	t.Run("list hides the jobs of other users", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"list"}, &model.CommandArgs{UserId: otherUserID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Equal(t, "No background jobs were found", resp.Text)
+	})
+
+	t.Run("admins list every job", func(t *testing.T) {
+		resp, _, err := plugin.runJobsCommand([]string{"list"}, &model.CommandArgs{UserId: adminID})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Text, job.ID)
+	})
+
+	t.Run("show job", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"show", job.ID}, &model.CommandArgs{UserId: ownerID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "- Command: `/wrangler move thread post1 channel1`")
+		assert.Contains(t, resp.Text, "- Status: pending")
+	})
+
+	t.Run("show job of another user", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"show", job.ID}, &model.CommandArgs{UserId: otherUserID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to find job")
+	})
+
+	t.Run("show invalid job ID", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"show", "not-a-job"}, &model.CommandArgs{UserId: ownerID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to find job")
+	})
+
+	t.Run("cancel job of another user", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"cancel", job.ID}, &model.CommandArgs{UserId: otherUserID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to find job")
+	})
+
+	t.Run("cancel job", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"cancel", job.ID}, &model.CommandArgs{UserId: ownerID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "has been canceled")
+	})
+
+	t.Run("cancel job that isn't pending", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"cancel", job.ID}, &model.CommandArgs{UserId: ownerID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "is canceled; only pending jobs can be canceled")
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/spf13/pflag"
@@ -16,22 +17,61 @@ const (
 	flagListMessagesTrimLength = "trim-length"
 	minListMessagesTrimLength  = 10
 	maxListMessagesTrimLength  = 500
+
+	flagListMessagesUser           = "user"
+	flagListMessagesSince          = "since"
+	flagListMessagesUntil          = "until"
+	flagListMessagesText           = "text"
+	flagListMessagesRootsOnly      = "roots-only"
+	flagListMessagesHasAttachments = "has-attachments"
+	flagListMessagesSystemMessages = "show-system-messages"
+
+	// listMessagesPageSize and maxListMessagesPages limit how many messages
+	// are searched when filters are used.
+	listMessagesPageSize = 100
+	maxListMessagesPages = 10
 )
 
 type listMessagesOptions struct {
-	count      int
-	trimLength int
+	count              int
+	trimLength         int
+	username           string
+	since              int64
+	until              int64
+	text               string
+	rootsOnly          bool
+	hasAttachments     bool
+	showSystemMessages bool
+}
+
+// isFiltered returns if any of the options can leave out messages.
+func (o *listMessagesOptions) isFiltered() bool {
+	return len(o.username) != 0 || o.since != 0 || o.until != 0 || len(o.text) != 0 ||
+		o.rootsOnly || o.hasAttachments || !o.showSystemMessages
+}
+
+// hasContentFilters returns if any of the options filter on what a message
+// contains, which system messages never match.
+func (o *listMessagesOptions) hasContentFilters() bool {
+	return len(o.username) != 0 || len(o.text) != 0 || o.hasAttachments
 }
 
 func getListMessagesFlagSet() *pflag.FlagSet {
 	listMessagesFlagSet := pflag.NewFlagSet("list messages", pflag.ContinueOnError)
 	listMessagesFlagSet.Int(flagListMessagesCount, 20, fmt.Sprintf("Number of messages to return. Must be between %d and %d", minListMessagesCount, maxListMessagesCount))
 	listMessagesFlagSet.Int(flagListMessagesTrimLength, 50, fmt.Sprintf("The max character count of messages listed before they are trimmed. Must be between %d and %d", minListMessagesTrimLength, maxListMessagesTrimLength))
+	listMessagesFlagSet.String(flagListMessagesUser, "", "Only list messages written by this user")
+	listMessagesFlagSet.String(flagListMessagesSince, "", "Only list messages posted after this time; an RFC 3339 timestamp or a duration before now, such as 24h")
+	listMessagesFlagSet.String(flagListMessagesUntil, "", "Only list messages posted before this time; an RFC 3339 timestamp or a duration before now, such as 2h")
+	listMessagesFlagSet.String(flagListMessagesText, "", "Only list messages that contain this text, ignoring case")
+	listMessagesFlagSet.Bool(flagListMessagesRootsOnly, false, "Only list messages that aren't replies in a thread")
+	listMessagesFlagSet.Bool(flagListMessagesHasAttachments, false, "Only list messages with file attachments")
+	listMessagesFlagSet.Bool(flagListMessagesSystemMessages, true, "Show a placeholder row for each system message")
 
 	return listMessagesFlagSet
 }
 
-func parseListMessagesArgs(args []string) (listMessagesOptions, error) {
+func parseListMessagesArgs(args []string, now time.Time) (listMessagesOptions, error) {
 	var options listMessagesOptions
 
 	listMessagesFlagSet := getListMessagesFlagSet()
@@ -56,30 +96,178 @@ func parseListMessagesArgs(args []string) (listMessagesOptions, error) {
 		return options, fmt.Errorf("%s (%d) must be between %d and %d", flagListMessagesTrimLength, options.trimLength, minListMessagesTrimLength, maxListMessagesTrimLength)
 	}
 
+	options.username, err = listMessagesFlagSet.GetString(flagListMessagesUser)
+	if err != nil {
+		return options, err
+	}
+	options.username = strings.TrimPrefix(options.username, "@")
+
+	since, err := listMessagesFlagSet.GetString(flagListMessagesSince)
+	if err != nil {
+		return options, err
+	}
+	if len(since) != 0 {
+		options.since, err = parseTimeFlag(since, now)
+		if err != nil {
+			return options, fmt.Errorf("%s: %s", flagListMessagesSince, err)
+		}
+	}
+
+	until, err := listMessagesFlagSet.GetString(flagListMessagesUntil)
+	if err != nil {
+		return options, err
+	}
+	if len(until) != 0 {
+		options.until, err = parseTimeFlag(until, now)
+		if err != nil {
+			return options, fmt.Errorf("%s: %s", flagListMessagesUntil, err)
+		}
+		if options.until < options.since {
+			return options, fmt.Errorf("--%s must be before --%s", flagListMessagesSince, flagListMessagesUntil)
+		}
+	}
+
+	options.text, err = listMessagesFlagSet.GetString(flagListMessagesText)
+	if err != nil {
+		return options, err
+	}
+
+	options.rootsOnly, err = listMessagesFlagSet.GetBool(flagListMessagesRootsOnly)
+	if err != nil {
+		return options, err
+	}
+
+	options.hasAttachments, err = listMessagesFlagSet.GetBool(flagListMessagesHasAttachments)
+	if err != nil {
+		return options, err
+	}
+
+	options.showSystemMessages, err = listMessagesFlagSet.GetBool(flagListMessagesSystemMessages)
+	if err != nil {
+		return options, err
+	}
+
 	return options, nil
 }
 
 func (p *Plugin) runListMessagesCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
-	options, err := parseListMessagesArgs(args)
+	options, err := parseListMessagesArgs(args, time.Now())
 	if err != nil {
 		return nil, true, err
 	}
 
-	channelPosts, appErr := p.API.GetPostsForChannel(extra.ChannelId, 0, options.count)
-	if appErr != nil {
-		return nil, false, appErr
+	var userID string
+	if len(options.username) != 0 {
+		user, appErr := p.API.GetUserByUsername(options.username)
+		if appErr != nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find user @%s", options.username)), true, nil
+		}
+		userID = user.Id
+	}
+
+	// Without filters, exactly the requested number of messages is fetched.
+	// With filters, pages of messages are searched until enough messages
+	// match or the search limit is reached.
+	perPage := options.count
+	maxPages := 1
+	if options.isFiltered() {
+		perPage = listMessagesPageSize
+		maxPages = maxListMessagesPages
 	}
 
-	msg := fmt.Sprintf("The last %d messages in this channel:\n", options.count)
-	for _, post := range channelPosts.ToSlice() {
+	var posts []*model.Post
+	var searchLimitReached bool
+	for page := 0; page < maxPages && len(posts) < options.count; page++ {
+		channelPosts, appErr := p.API.GetPostsForChannel(extra.ChannelId, page, perPage)
+		if appErr != nil {
+			return nil, false, appErr
+		}
+
+		pagePosts := channelPosts.ToSlice()
+		var reachedSince bool
+		for _, post := range pagePosts {
+			if options.since != 0 && post.CreateAt < options.since {
+				// Posts are returned newest first, so every later post is
+				// also too old.
+				reachedSince = true
+				break
+			}
+			if !listMessagesPostMatches(post, options, userID) {
+				continue
+			}
+			posts = append(posts, post)
+			if len(posts) == options.count {
+				break
+			}
+		}
+		if reachedSince || len(pagePosts) < perPage {
+			break
+		}
+		if page == maxPages-1 && options.isFiltered() {
+			searchLimitReached = true
+		}
+	}
+
+	var msg string
+	if options.isFiltered() {
+		msg = fmt.Sprintf("%d messages in this channel match the filters:\n", len(posts))
+	} else {
+		msg = fmt.Sprintf("The last %d messages in this channel:\n", options.count)
+	}
+
+	usernames := make(map[string]string)
+	for _, post := range posts {
 		if post.IsSystemMessage() {
 			msg += "[     system message     ] - <skipped>\n"
-		} else {
-			msg += fmt.Sprintf("%s - %s\n", post.Id, cleanAndTrimMessage(post.Message, options.trimLength))
+			continue
+		}
+
+		replies := "reply"
+		if len(post.RootId) == 0 {
+			replies = fmt.Sprintf("%d replies", post.ReplyCount)
+			if post.ReplyCount == 1 {
+				replies = "1 reply"
+			}
 		}
+		msg += fmt.Sprintf("%s - %s - @%s - %s - %s\n",
+			post.Id,
+			formatTimestamp(post.CreateAt),
+			p.getCachedUsername(post.UserId, usernames),
+			replies,
+			cleanAndTrimMessage(post.Message, options.trimLength),
+		)
+	}
+	if searchLimitReached && len(posts) < options.count {
+		msg += fmt.Sprintf("Only the last %d messages in this channel were searched\n", listMessagesPageSize*maxListMessagesPages)
 	}
 
 	msg = codeBlock(strings.TrimRight(msg, "\n"))
 
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
 }
+
+// listMessagesPostMatches returns if a post should be listed with the given
+// options. Posts that are newer than the until time are left out; the since
+// time is checked while paging through the channel.
+func listMessagesPostMatches(post *model.Post, options listMessagesOptions, userID string) bool {
+	if options.until != 0 && post.CreateAt > options.until {
+		return false
+	}
+	if options.rootsOnly && len(post.RootId) != 0 {
+		return false
+	}
+	if post.IsSystemMessage() {
+		return options.showSystemMessages && !options.hasContentFilters()
+	}
+	if len(userID) != 0 && post.UserId != userID {
+		return false
+	}
+	if len(options.text) != 0 && !strings.Contains(strings.ToLower(post.Message), strings.ToLower(options.text)) {
+		return false
+	}
+	if options.hasAttachments && len(post.FileIds) == 0 {
+		return false
+	}
+
+	return true
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -20,6 +21,7 @@ func TestMessagelListCommand(t *testing.T) {
 
 	api := &plugintest.API{}
 	api.On("GetPostsForChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(testPostList, nil)
+	api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{Username: "author"}, nil)
 
 	var plugin Plugin
 	plugin.SetAPI(api)
@@ -31,6 +33,7 @@ func TestMessagelListCommand(t *testing.T) {
 		for _, post := range testPostList.ToSlice() {
 			assert.Contains(t, resp.Text, post.Id)
 			assert.Contains(t, resp.Text, post.Message)
+			assert.Contains(t, resp.Text, fmt.Sprintf("%s - %s - @author - 0 replies - %s", post.Id, formatTimestamp(post.CreateAt), post.Message))
 		}
 	})
 
@@ -99,6 +102,88 @@ func TestMessagelListCommand(t *testing.T) {
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, "[     system message     ] - <skipped>")
+
+		resp, isUserError, err = plugin.runListMessagesCommand([]string{"--show-system-messages=false"}, &model.CommandArgs{ChannelId: testChannel.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "0 messages in this channel match the filters")
+		assert.NotContains(t, resp.Text, "system message")
+	})
+}
+
+func TestListMessagesFilters(t *testing.T) {
+	channelID := model.NewId()
+	alice := &model.User{Id: model.NewId(), Username: "alice"}
+	bob := &model.User{Id: model.NewId(), Username: "bob"}
+	now := model.GetMillis()
+
+	root := &model.Post{Id: model.NewId(), UserId: alice.Id, Message: "Where is the deploy guide?", CreateAt: now - 3*60*60*1000, ReplyCount: 1}
+	reply := &model.Post{Id: model.NewId(), UserId: bob.Id, RootId: root.Id, Message: "Here, see the attached file", FileIds: []string{model.NewId()}, CreateAt: now - 2*60*60*1000}
+	latest := &model.Post{Id: model.NewId(), UserId: bob.Id, Message: "Lunch?", CreateAt: now - 60*1000}
+	system := &model.Post{Id: model.NewId(), UserId: alice.Id, Type: model.POST_JOIN_CHANNEL, CreateAt: now - 30*1000}
+
+	postList := model.NewPostList()
+	for _, post := range []*model.Post{system, latest, reply, root} {
+		postList.AddPost(post)
+		postList.AddOrder(post.Id)
+	}
+
+	api := &plugintest.API{}
+	api.On("GetPostsForChannel", channelID, 0, listMessagesPageSize).Return(postList, nil)
+	api.On("GetUser", alice.Id).Return(alice, nil)
+	api.On("GetUser", bob.Id).Return(bob, nil)
+	api.On("GetUserByUsername", "bob").Return(bob, nil)
+	api.On("GetUserByUsername", "nobody").Return(nil, model.NewAppError("GetUserByUsername", "not.found", nil, "", 404))
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	run := func(t *testing.T, args ...string) string {
+		resp, isUserError, err := plugin.runListMessagesCommand(args, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		require.False(t, isUserError)
+		return resp.Text
+	}
+
+	t.Run("user", func(t *testing.T) {
+		text := run(t, "--user", "@bob")
+		assert.Contains(t, text, "2 messages in this channel match the filters")
+		assert.Contains(t, text, fmt.Sprintf("%s - %s - @bob - reply - %s", reply.Id, formatTimestamp(reply.CreateAt), reply.Message))
+		assert.Contains(t, text, latest.Id)
+		assert.NotContains(t, text, root.Id)
+		assert.NotContains(t, text, "system message")
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListMessagesCommand([]string{"--user", "nobody"}, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Equal(t, "Error: unable to find user @nobody", resp.Text)
+	})
+
+	t.Run("time range", func(t *testing.T) {
+		text := run(t, "--since", "150m", "--until", "30m")
+		assert.Contains(t, text, "1 messages in this channel match the filters")
+		assert.Contains(t, text, reply.Id)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		text := run(t, "--text", "DEPLOY")
+		assert.Contains(t, text, fmt.Sprintf("%s - %s - @alice - 1 reply - %s", root.Id, formatTimestamp(root.CreateAt), root.Message))
+		assert.NotContains(t, text, reply.Id)
+	})
+
+	t.Run("roots only", func(t *testing.T) {
+		text := run(t, "--roots-only")
+		assert.Contains(t, text, "3 messages in this channel match the filters")
+		assert.Contains(t, text, "[     system message     ] - <skipped>")
+		assert.NotContains(t, text, reply.Id)
+	})
+
+	t.Run("has attachments", func(t *testing.T) {
+		text := run(t, "--has-attachments")
+		assert.Contains(t, text, "1 messages in this channel match the filters")
+		assert.Contains(t, text, reply.Id)
 	})
 }
 
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagListThreadsCount = "count"
+	minListThreadsCount  = 1
+	maxListThreadsCount  = 50
+
+	flagListThreadsSince = "since"
+
+	listThreadsTrimLength = 50
+
+	// listThreadsPageSize and maxListThreadsPages limit how many recent
+	// messages are searched for active threads.
+	listThreadsPageSize = 200
+	maxListThreadsPages = 5
+)
+
+type listThreadsOptions struct {
+	count int
+	since int64
+}
+
+func getListThreadsFlagSet() *pflag.FlagSet {
+	listThreadsFlagSet := pflag.NewFlagSet("list threads", pflag.ContinueOnError)
+	listThreadsFlagSet.Int(flagListThreadsCount, 10, fmt.Sprintf("Number of threads to return. Must be between %d and %d", minListThreadsCount, maxListThreadsCount))
+	listThreadsFlagSet.String(flagListThreadsSince, "", "Only list threads with activity after this time; an RFC 3339 timestamp or a duration before now, such as 24h")
+
+	return listThreadsFlagSet
+}
+
+func parseListThreadsArgs(args []string, now time.Time) (listThreadsOptions, error) {
+	var options listThreadsOptions
+
+	listThreadsFlagSet := getListThreadsFlagSet()
+	err := listThreadsFlagSet.Parse(args)
+	if err != nil {
+		return options, err
+	}
+
+	options.count, err = listThreadsFlagSet.GetInt(flagListThreadsCount)
+	if err != nil {
+		return options, err
+	}
+	if options.count < minListThreadsCount || options.count > maxListThreadsCount {
+		return options, fmt.Errorf("%s (%d) must be between %d and %d", flagListThreadsCount, options.count, minListThreadsCount, maxListThreadsCount)
+	}
+
+	since, err := listThreadsFlagSet.GetString(flagListThreadsSince)
+	if err != nil {
+		return options, err
+	}
+	if len(since) != 0 {
+		options.since, err = parseTimeFlag(since, now)
+		if err != nil {
+			return options, fmt.Errorf("%s: %s", flagListThreadsSince, err)
+		}
+	}
+
+	return options, nil
+}
+
+func (p *Plugin) runListThreadsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	options, err := parseListThreadsArgs(args, time.Now())
+	if err != nil {
+		return nil, true, err
+	}
+
+	rootIDs, err := p.getRecentlyActiveRootIDs(extra.ChannelId, options)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var threads []*WranglerPostList
+	for _, rootID := range rootIDs {
+		postList, appErr := p.API.GetPostThread(rootID)
+		if appErr != nil {
+			// The root of a thread can be deleted while its replies are
+			// still being loaded, so the thread is left out.
+			p.API.LogError("Unable to get thread to list",
+				"root_id", rootID,
+				"err", appErr,
+			)
+			continue
+		}
+		threads = append(threads, buildWranglerPostList(postList))
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i].LatestPostTimestamp > threads[j].LatestPostTimestamp
+	})
+
+	if len(threads) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No recent threads were found in this channel"), false, nil
+	}
+
+	msg := fmt.Sprintf("The %d most recently active threads in this channel:\n", len(threads))
+	for _, wpl := range threads {
+		msg += fmt.Sprintf("%s - %s - %d replies - %d participants - %s\n",
+			wpl.RootPost().Id,
+			formatTimestamp(wpl.LatestPostTimestamp),
+			wpl.NumPosts()-1,
+			len(wpl.ThreadUserIDs),
+			cleanAndTrimMessage(wpl.RootPost().Message, listThreadsTrimLength),
+		)
+	}
+
+	msg = codeBlock(strings.TrimRight(msg, "\n"))
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+// getRecentlyActiveRootIDs returns the IDs of the root posts of the threads in
+// the channel with the most recent activity, most recently active first.
+// Channel posts are returned newest first, so a thread's latest activity is
+// where its first post shows up, whether that is the root post or a reply.
+func (p *Plugin) getRecentlyActiveRootIDs(channelID string, options listThreadsOptions) ([]string, error) {
+	var rootIDs []string
+	seen := make(map[string]bool)
+
+	for page := 0; page < maxListThreadsPages; page++ {
+		channelPosts, appErr := p.API.GetPostsForChannel(channelID, page, listThreadsPageSize)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		pagePosts := channelPosts.ToSlice()
+		for _, post := range pagePosts {
+			if options.since != 0 && post.CreateAt < options.since {
+				return rootIDs, nil
+			}
+			if post.IsSystemMessage() {
+				continue
+			}
+
+			rootID := post.RootId
+			if len(rootID) == 0 {
+				rootID = post.Id
+			}
+			if seen[rootID] {
+				continue
+			}
+			seen[rootID] = true
+
+			rootIDs = append(rootIDs, rootID)
+			if len(rootIDs) == options.count {
+				return rootIDs, nil
+			}
+		}
+		if len(pagePosts) < listThreadsPageSize {
+			break
+		}
+	}
+
+	return rootIDs, nil
+}
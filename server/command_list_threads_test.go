@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListThreadsCommand(t *testing.T) {
+	channelID := model.NewId()
+	now := model.GetMillis()
+	minute := int64(60 * 1000)
+
+	// The busy thread was started first but has the latest reply.
+	busyRoot := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: channelID, Message: "Production is down", CreateAt: now - 60*minute}
+	busyReply1 := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: channelID, RootId: busyRoot.Id, Message: "Looking", CreateAt: now - 50*minute}
+	busyReply2 := &model.Post{Id: model.NewId(), UserId: busyRoot.UserId, ChannelId: channelID, RootId: busyRoot.Id, Message: "Fixed", CreateAt: now - 5*minute}
+	quietRoot := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: channelID, Message: "Lunch?", CreateAt: now - 20*minute}
+	oldRoot := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: channelID, Message: "Old news", CreateAt: now - 24*60*minute}
+	system := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: channelID, Type: model.POST_JOIN_CHANNEL, CreateAt: now - minute}
+
+	channelPosts := model.NewPostList()
+	for _, post := range []*model.Post{system, busyReply2, quietRoot, busyReply1, busyRoot, oldRoot} {
+		channelPosts.AddPost(post)
+		channelPosts.AddOrder(post.Id)
+	}
+	threadList := func(posts ...*model.Post) *model.PostList {
+		postList := model.NewPostList()
+		for _, post := range posts {
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+		return postList
+	}
+
+	api := &plugintest.API{}
+	api.On("GetPostsForChannel", channelID, 0, listThreadsPageSize).Return(channelPosts, nil)
+	api.On("GetPostThread", busyRoot.Id).Return(threadList(busyRoot, busyReply1, busyReply2), nil)
+	api.On("GetPostThread", quietRoot.Id).Return(threadList(quietRoot), nil)
+	api.On("GetPostThread", oldRoot.Id).Return(nil, model.NewAppError("GetPostThread", "not.found", nil, "", 404))
+	api.On("LogError", mock.AnythingOfTypeArgument("string"), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("threads sorted by activity", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListThreadsCommand([]string{"--count", "2"}, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "The 2 most recently active threads in this channel:\n")
+		assert.Contains(t, resp.Text, fmt.Sprintf("%s - %s - 2 replies - 2 participants - Production is down\n%s - %s - 0 replies - 1 participants - Lunch?",
+			busyRoot.Id, formatTimestamp(busyReply2.CreateAt),
+			quietRoot.Id, formatTimestamp(quietRoot.CreateAt),
+		))
+		assert.NotContains(t, resp.Text, oldRoot.Id)
+	})
+
+	t.Run("since", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListThreadsCommand([]string{"--since", "10m"}, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "The 1 most recently active threads in this channel:\n")
+		assert.Contains(t, resp.Text, busyRoot.Id)
+		assert.NotContains(t, resp.Text, quietRoot.Id)
+	})
+
+	t.Run("deleted threads are left out", func(t *testing.T) {
+		resp, _, err := plugin.runListThreadsCommand([]string{}, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Text, "The 2 most recently active threads in this channel:\n")
+	})
+
+	t.Run("no threads", func(t *testing.T) {
+		resp, _, err := plugin.runListThreadsCommand([]string{"--since", "30s"}, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		assert.Equal(t, "No recent threads were found in this channel", resp.Text)
+	})
+}
+
+func TestParseListThreadsArgs(t *testing.T) {
+	now := time.Now()
+
+	t.Run("defaults", func(t *testing.T) {
+		options, err := parseListThreadsArgs([]string{}, now)
+		require.NoError(t, err)
+		assert.Equal(t, 10, options.count)
+		assert.Zero(t, options.since)
+	})
+
+	t.Run("count out of range", func(t *testing.T) {
+		_, err := parseListThreadsArgs([]string{"--count=0"}, now)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "count (0) must be between 1 and 50")
+
+		_, err = parseListThreadsArgs([]string{"--count=51"}, now)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		_, err := parseListThreadsArgs([]string{"--since=yesterday"}, now)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const mergeThreadUsage = `/wrangler merge thread [SOURCE_ROOT_ID] [TARGET_ROOT_ID]
+  Merge a given thread into another existing thread
+    - The target thread can be in any channel in any team that you have joined
+    - Replies from both threads are ordered by when they were originally posted
+    - Obtain the message IDs by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
+	Flags:
+%s`
+
+type mergeThreadOptions struct {
+	preserveTimestamps bool
+	silent             bool
+}
+
+func getMergeThreadFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("merge thread", pflag.ContinueOnError)
+	flagSet.Bool(flagPreserveTimestamps, true, "Keep the original timestamps so that replies from both threads are ordered by when they were posted; when false, the merged messages are added after the existing replies")
+	flagSet.Bool(flagSilent, true, "Don't notify users who are mentioned in the merged messages again by adding a zero-width space after each @ in the stored text")
+
+	return flagSet
+}
+
+func parseMergeThreadFlagArgs(args []string) (mergeThreadOptions, error) {
+	var options mergeThreadOptions
+
+	flagSet := getMergeThreadFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, errors.Wrap(err, "unable to parse merge thread flag args")
+	}
+
+	options.preserveTimestamps, err = flagSet.GetBool(flagPreserveTimestamps)
+	if err != nil {
+		return options, err
+	}
+
+	options.silent, err = flagSet.GetBool(flagSilent)
+	if err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+func getMergeThreadUsage() string {
+	return fmt.Sprintf(mergeThreadUsage, getMergeThreadFlagSet().FlagUsages())
+}
+
+func getMergeThreadMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getMergeThreadUsage()))
+}
+
+func (p *Plugin) runMergeThreadCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 2 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getMergeThreadMessage()), true, nil
+	}
+	options, err := parseMergeThreadFlagArgs(args)
+	if err != nil {
+		return nil, true, err
+	}
+	sourcePostID := args[0]
+	targetPostID := args[1]
+
+	if sourcePostID == targetPostID {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the two provided message IDs should not be the same"), true, nil
+	}
+
+	sourcePostList, appErr := p.API.GetPostThread(sourcePostID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", sourcePostID)), true, nil
+	}
+	targetPostList, appErr := p.API.GetPostThread(targetPostID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", targetPostID)), true, nil
+	}
+	sourceWPL := buildWranglerPostList(sourcePostList)
+	targetWPL := buildWranglerPostList(targetPostList)
+	if targetWPL.NumPosts() == 0 {
+		return nil, false, errors.New("The target wrangler post list contains no posts")
+	}
+	if sourceWPL.RootPost() != nil && sourceWPL.RootPost().Id == targetWPL.RootPost().Id {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the two provided messages are already in the same thread"), true, nil
+	}
+	targetRootPost := targetWPL.RootPost()
+
+	originalChannel, appErr := p.API.GetChannel(extra.ChannelId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
+	}
+	_, appErr = p.API.GetChannelMember(targetRootPost.ChannelId, extra.UserId)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", targetRootPost.ChannelId)), true, nil
+	}
+	targetChannel, appErr := p.API.GetChannel(targetRootPost.ChannelId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", targetRootPost.ChannelId)
+	}
+
+	response, userErr, err := p.validateMoveOrCopy(operationMerge, sourceWPL, originalChannel, targetChannel, extra)
+	if response != nil || err != nil {
+		return response, userErr, err
+	}
+
+	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+	}
+
+	p.API.LogInfo("Wrangler is merging a thread",
+		"user_id", extra.UserId,
+		"original_post_id", sourceWPL.RootPost().Id,
+		"target_post_id", targetRootPost.Id,
+	)
+
+	audit := newAuditEntry(operationMerge, extra.UserId, originalChannel.Id, targetChannel.Id, sourceWPL.Posts)
+	defer p.recordAuditEntry(audit)
+
+	journal, err := p.startJournal(operationMerge, extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The combined post list is only used to count the messages of the
+	// merged thread.
+	mergedWPL := buildWranglerPostList(targetPostList, sourcePostList)
+	_, err = p.copyWranglerPostlist(sourceWPL, targetChannel, copyOptions{
+		preserveTimestamps: options.preserveTimestamps,
+		silent:             options.silent,
+		operation:          operationMerge,
+		userID:             extra.UserId,
+		originalTeamID:     originalChannel.TeamId,
+		targetRootPost:     targetRootPost,
+	}, journal)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	botPost, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		RootId:    targetRootPost.Id,
+		ParentId:  targetRootPost.Id,
+		ChannelId: targetChannel.Id,
+		Message:   fmt.Sprintf("%d messages from another thread were merged into this thread", sourceWPL.NumPosts()),
+	})
+	if appErr != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to create new bot post"))
+	}
+	err = p.journalPost(journal, botPost.Id)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	err = p.cleanupOriginalPosts(sourceWPL, journal)
+	if err != nil {
+		return nil, false, err
+	}
+	p.clearUndoRecord(extra.UserId)
+
+	p.API.LogInfo("Wrangler thread merge complete",
+		"user_id", extra.UserId,
+		"target_post_id", targetRootPost.Id,
+		"target_channel_id", targetChannel.Id,
+	)
+
+	targetPostLink := makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, targetRootPost.Id)
+	if extra.UserId != sourceWPL.RootPost().UserId {
+		// The merged thread was not started by the user running the command.
+		// Send a DM to the user who created the root message to let them know.
+		err := p.postMergeThreadBotDM(sourceWPL.RootPost().UserId, targetPostLink)
+		if err != nil {
+			p.API.LogError("Unable to send merge-thread DM to user",
+				"error", err.Error(),
+				"user_id", sourceWPL.RootPost().UserId,
+			)
+		}
+	}
+
+	msg := fmt.Sprintf("A thread has been merged into another thread: %s\n", targetPostLink)
+	msg += fmt.Sprintf(
+		"\n| Team | Channel | Messages Merged | Thread Messages |\n| -- | -- | -- | -- |\n| %s | %s | %d | %d |\n",
+		targetTeam.DisplayName, targetChannel.DisplayName, sourceWPL.NumPosts(), mergedWPL.NumPosts(),
+	)
+
+	audit.succeeded()
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, msg), false, nil
+}
+
+func (p *Plugin) postMergeThreadBotDM(userID, targetPostLink string) error {
+	return p.PostBotDM(userID, fmt.Sprintf(
+		"Someone wrangled a thread you started into another thread for you: %s", targetPostLink,
+	))
+}
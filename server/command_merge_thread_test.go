@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeThreadCommand(t *testing.T) {
+	team1 := &model.Team{
+		Id:          model.NewId(),
+		Name:        "team-1",
+		DisplayName: "Team 1",
+	}
+	originalChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+		Name:   "original-channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+	targetChannel := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "target-channel",
+		DisplayName: "Target Channel",
+		Type:        model.CHANNEL_OPEN,
+	}
+
+	sourcePosts := mockGeneratePostList(3, originalChannel.Id, false)
+	targetPosts := mockGeneratePostList(2, targetChannel.Id, false)
+	sourceSlice := sourcePosts.ToSlice()
+	sourceRootID := sourceSlice[len(sourceSlice)-1].Id
+	targetSlice := targetPosts.ToSlice()
+	targetRootID := targetSlice[len(targetSlice)-1].Id
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	api := &plugintest.API{}
+	api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+	api.On("GetChannel", targetChannel.Id).Return(targetChannel, nil)
+	api.On("GetPostThread", "source").Return(sourcePosts, nil)
+	api.On("GetPostThread", "target").Return(targetPosts, nil)
+	api.On("GetPostThread", mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetPostThread", "not.found", nil, "", 404))
+	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.Channel{Id: model.NewId()}, nil)
+	api.On("GetTeam", mock.AnythingOfType("string")).Return(team1, nil)
+	var createdPosts []*model.Post
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil).Run(func(args mock.Arguments) {
+		createdPosts = append(createdPosts, args.Get(0).(*model.Post))
+	})
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	api.On("GetConfig").Return(config)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("same IDs", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{"source", "source"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the two provided message IDs should not be the same")
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{"source", "invalid"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to get post with ID invalid")
+	})
+
+	t.Run("not in source channel", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{"source", "target"}, &model.CommandArgs{ChannelId: targetChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: this command must be run from the channel containing the post")
+	})
+
+	t.Run("merge thread successfully", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{"source", "target"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, fmt.Sprintf("A thread has been merged into another thread: %s", makePostLink(*config.ServiceSettings.SiteURL, team1.Name, targetRootID)))
+		assert.Contains(t, resp.Text, fmt.Sprintf("| %s | %s | %d | %d |", team1.DisplayName, targetChannel.DisplayName, 3, 5))
+		api.AssertCalled(t, "DeletePost", sourceRootID)
+	})
+
+	t.Run("merged messages are copied as replies to the target root", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{})
+		sourcePosts.Posts[sourceRootID].Message = "@channel please review"
+		sourcePosts.Posts[sourceRootID].IsPinned = true
+		defer func() {
+			sourcePosts.Posts[sourceRootID].Message = "This is message 1"
+			sourcePosts.Posts[sourceRootID].IsPinned = false
+		}()
+
+		// The user started the source thread, so no DM is sent.
+		extra := &model.CommandArgs{ChannelId: originalChannel.Id, UserId: sourcePosts.Posts[sourceRootID].UserId}
+
+		createdPosts = nil
+		_, isUserError, err := plugin.runMergeThreadCommand([]string{"source", "target", "--silent=false", "--preserve-timestamps=false"}, extra)
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+
+		// The merged messages are followed by the bot notice.
+		require.Len(t, createdPosts, 4)
+		for _, post := range createdPosts {
+			assert.Equal(t, targetRootID, post.RootId)
+			assert.Equal(t, targetChannel.Id, post.ChannelId)
+		}
+		assert.Equal(t, "@channel please review", createdPosts[0].Message)
+		assert.True(t, createdPosts[0].IsPinned)
+		assert.Zero(t, createdPosts[0].CreateAt)
+
+		createdPosts = nil
+		_, _, err = plugin.runMergeThreadCommand([]string{"source", "target"}, extra)
+		require.NoError(t, err)
+		require.Len(t, createdPosts, 4)
+		assert.Equal(t, "@\u200bchannel please review", createdPosts[0].Message)
+		assert.Equal(t, sourcePosts.Posts[sourceRootID].CreateAt, createdPosts[0].CreateAt)
+	})
+}
+
+func TestBuildWranglerPostListFromMultipleLists(t *testing.T) {
+	first := mockGeneratePostList(3, model.NewId(), false)
+	second := mockGeneratePostList(2, model.NewId(), false)
+
+	wpl := buildWranglerPostList(first, second)
+	require.Equal(t, 5, wpl.NumPosts())
+	for i := 1; i < wpl.NumPosts(); i++ {
+		assert.True(t, wpl.Posts[i-1].CreateAt <= wpl.Posts[i].CreateAt)
+	}
+	assert.Len(t, wpl.ThreadUserIDs, 5)
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	moveOrCopyMessagesUsage = `/wrangler %[1]s messages [flags] [CHANNEL_ID]
+  %[2]s every message in this channel within a range, along with their threads, to a given channel
+    - Set the range with either the --from and --to message IDs or the --since and --until times
+    - Times are RFC 3339 timestamps, such as 2020-06-01T15:04:05Z, or durations before now, such as 2h
+    - Use the '/wrangler list' commands to get message and channel IDs
+	Flags:
+%[3]s`
+
+	flagMessagesFrom  = "from"
+	flagMessagesTo    = "to"
+	flagMessagesSince = "since"
+	flagMessagesUntil = "until"
+)
+
+type moveMessagesOptions struct {
+	channelID          string
+	fromPostID         string
+	toPostID           string
+	since              int64
+	until              int64
+	preserveTimestamps bool
+	silent             bool
+	participants       string
+}
+
+func getMoveOrCopyMessagesFlagSet(operation string) *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet(fmt.Sprintf("%s messages", operation), pflag.ContinueOnError)
+	flagSet.String(flagMessagesFrom, "", "The ID of the first message in the range")
+	flagSet.String(flagMessagesTo, "", "The ID of the last message in the range (defaults to the latest message)")
+	flagSet.String(flagMessagesSince, "", "The start time of the range")
+	flagSet.String(flagMessagesUntil, "", "The end time of the range (defaults to now)")
+	flagSet.Bool(flagPreserveTimestamps, false, fmt.Sprintf("Keep the original timestamps of the %s messages (defaults to the plugin configuration)", pastTense(operation)))
+	flagSet.Bool(flagSilent, true, fmt.Sprintf("Don't notify users who are mentioned in the %s messages again by adding a zero-width space after each @ in the stored text", pastTense(operation)))
+	addParticipantsFlag(flagSet)
+
+	return flagSet
+}
+
+func parseMoveOrCopyMessagesFlagArgs(operation string, args []string, preserveTimestampsDefault bool, now time.Time) (moveMessagesOptions, error) {
+	var options moveMessagesOptions
+
+	flagSet := getMoveOrCopyMessagesFlagSet(operation)
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, errors.Wrapf(err, "unable to parse %s messages flag args", operation)
+	}
+	if flagSet.NArg() != 1 {
+		return options, errors.New("a single target channel ID must be provided")
+	}
+	options.channelID = flagSet.Arg(0)
+
+	options.fromPostID, err = flagSet.GetString(flagMessagesFrom)
+	if err != nil {
+		return options, err
+	}
+	options.toPostID, err = flagSet.GetString(flagMessagesTo)
+	if err != nil {
+		return options, err
+	}
+	since, err := flagSet.GetString(flagMessagesSince)
+	if err != nil {
+		return options, err
+	}
+	until, err := flagSet.GetString(flagMessagesUntil)
+	if err != nil {
+		return options, err
+	}
+
+	if len(options.fromPostID) == 0 && len(since) == 0 {
+		return options, fmt.Errorf("either --%s or --%s must be provided", flagMessagesFrom, flagMessagesSince)
+	}
+	if (len(options.fromPostID) != 0 || len(options.toPostID) != 0) && (len(since) != 0 || len(until) != 0) {
+		return options, fmt.Errorf("message ID flags and time flags cannot be used together")
+	}
+
+	if len(since) != 0 {
+		options.since, err = parseTimeFlag(since, now)
+		if err != nil {
+			return options, errors.Wrapf(err, "invalid --%s value", flagMessagesSince)
+		}
+	}
+	if len(until) != 0 {
+		options.until, err = parseTimeFlag(until, now)
+		if err != nil {
+			return options, errors.Wrapf(err, "invalid --%s value", flagMessagesUntil)
+		}
+		if options.until < options.since {
+			return options, fmt.Errorf("--%s must be before --%s", flagMessagesSince, flagMessagesUntil)
+		}
+	}
+
+	options.preserveTimestamps, err = getBoolFlagWithDefault(flagSet, flagPreserveTimestamps, preserveTimestampsDefault)
+	if err != nil {
+		return options, err
+	}
+
+	options.silent, err = flagSet.GetBool(flagSilent)
+	if err != nil {
+		return options, err
+	}
+
+	options.participants, err = getParticipantsFlag(flagSet)
+	if err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+func getMoveMessagesUsage() string {
+	return fmt.Sprintf(moveOrCopyMessagesUsage, operationMove, "Move", getMoveOrCopyMessagesFlagSet(operationMove).FlagUsages())
+}
+
+func getCopyMessagesUsage() string {
+	return fmt.Sprintf(moveOrCopyMessagesUsage, operationCopy, "Copy", getMoveOrCopyMessagesFlagSet(operationCopy).FlagUsages())
+}
+
+func pastTense(operation string) string {
+	if operation == operationCopy {
+		return "copied"
+	}
+
+	return "moved"
+}
+
+func (p *Plugin) runMoveMessagesCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	return p.runMoveOrCopyMessagesCommand(operationMove, args, extra, nil)
+}
+
+func (p *Plugin) runCopyMessagesCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	return p.runMoveOrCopyMessagesCommand(operationCopy, args, extra, nil)
+}
+
+// runMoveOrCopyMessagesCommand moves or copies a range of messages. The job
+// is the background job running the command, or nil if the command wasn't
+// queued.
+func (p *Plugin) runMoveOrCopyMessagesCommand(operation string, args []string, extra *model.CommandArgs, job *WranglerJob) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		usage := getMoveMessagesUsage()
+		if operation == operationCopy {
+			usage = getCopyMessagesUsage()
+		}
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", usage))), true, nil
+	}
+	now := time.Now()
+	options, err := parseMoveOrCopyMessagesFlagArgs(operation, args, p.getConfiguration().PreserveTimestampsByDefault, now)
+	if err != nil {
+		return nil, true, err
+	}
+	if job != nil {
+		// Relative times are measured from when the job was queued rather
+		// than from when it runs.
+		now = time.Unix(0, job.CreateAt*int64(time.Millisecond))
+		options, err = parseMoveOrCopyMessagesFlagArgs(operation, args, p.getConfiguration().PreserveTimestampsByDefault, now)
+		if err != nil {
+			return nil, true, err
+		}
+	}
+
+	start, end := options.since, options.until
+	if len(options.fromPostID) != 0 {
+		start, end, err = p.getMessageRangeTimestamps(options.fromPostID, options.toPostID, extra.ChannelId)
+		if err != nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: %s", err.Error())), true, nil
+		}
+	}
+	if end == 0 {
+		// Leave out messages posted after the command was run.
+		end = model.GetMillisForTime(now)
+	}
+
+	channelPosts, err := p.getChannelPostsSince(extra.ChannelId, start)
+	if err != nil {
+		return nil, false, err
+	}
+	wpls := buildThreadWranglerPostLists(channelPosts, start, end)
+	if len(wpls) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: no messages were found in the given range"), true, nil
+	}
+
+	originalChannel, appErr := p.API.GetChannel(extra.ChannelId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
+	}
+	_, appErr = p.API.GetChannelMember(options.channelID, extra.UserId)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", options.channelID)), true, nil
+	}
+	targetChannel, appErr := p.API.GetChannel(options.channelID)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", options.channelID)
+	}
+
+	// The limits apply to every thread in the range together, as they are
+	// all wrangled by this command.
+	response, userErr, err := p.validateMoveOrCopyPostLists(operation, wpls, originalChannel, targetChannel, extra)
+	if response != nil || err != nil {
+		return response, userErr, err
+	}
+	var postCount int
+	for _, wpl := range wpls {
+		postCount += wpl.NumPosts()
+	}
+
+	response, err = p.queueLargeJob(fmt.Sprintf("%s messages", operation), job, postCount, args, extra)
+	if response != nil || err != nil {
+		return response, false, err
+	}
+
+	nonMemberIDs, response, err := p.prepareNonMemberParticipants(options.participants, wpls, targetChannel, extra.UserId)
+	if response != nil || err != nil {
+		// The participants option refusing the operation is a user error.
+		return response, response != nil, err
+	}
+
+	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+	}
+
+	p.API.LogInfo("Wrangler is wrangling a range of messages",
+		"user_id", extra.UserId,
+		"operation", operation,
+		"original_channel_id", originalChannel.Id,
+	)
+
+	var posts []*model.Post
+	for _, wpl := range wpls {
+		posts = append(posts, wpl.Posts...)
+	}
+	audit := newAuditEntry(operation, extra.UserId, originalChannel.Id, targetChannel.Id, posts)
+	defer p.recordAuditEntry(audit)
+
+	journal, err := p.startJournal(operation, extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	copyOpts := copyOptions{
+		preserveTimestamps: options.preserveTimestamps,
+		silent:             options.silent,
+		operation:          operation,
+		userID:             extra.UserId,
+		originalTeamID:     originalChannel.TeamId,
+	}
+	newRootPosts := make([]*model.Post, len(wpls))
+	for i, wpl := range wpls {
+		newRootPosts[i], err = p.copyWranglerPostlist(wpl, targetChannel, copyOpts, journal)
+		if err != nil {
+			return nil, false, p.rollbackJournalAndWrap(journal, err)
+		}
+
+		botPost, appErr := p.API.CreatePost(&model.Post{
+			UserId:    p.BotUserID,
+			RootId:    newRootPosts[i].Id,
+			ParentId:  newRootPosts[i].Id,
+			ChannelId: targetChannel.Id,
+			Message:   fmt.Sprintf("This thread was %s from another channel", pastTense(operation)),
+		})
+		if appErr != nil {
+			return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to create new bot post"))
+		}
+		err = p.journalPost(journal, botPost.Id)
+		if err != nil {
+			return nil, false, p.rollbackJournalAndWrap(journal, err)
+		}
+	}
+
+	if operation == operationMove {
+		err = p.cleanupOriginalPostLists(wpls, journal)
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		p.completeJournal(journal)
+	}
+	p.clearUndoRecord(extra.UserId)
+
+	p.API.LogInfo("Wrangler message range complete",
+		"user_id", extra.UserId,
+		"operation", operation,
+		"new_channel_id", targetChannel.Id,
+	)
+
+	// Let each author know once, linking to the first of their threads.
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	notified := make(map[string]bool)
+	for i, wpl := range wpls {
+		authorID := wpl.RootPost().UserId
+		if authorID == extra.UserId || notified[authorID] {
+			continue
+		}
+		notified[authorID] = true
+
+		err := p.postMoveOrCopyMessagesBotDM(authorID, operation, makePostLink(siteURL, targetTeam.Name, newRootPosts[i].Id))
+		if err != nil {
+			p.API.LogError(fmt.Sprintf("Unable to send %s-messages DM to user", operation),
+				"error", err.Error(),
+				"user_id", authorID,
+			)
+		}
+	}
+
+	newPostLink := makePostLink(siteURL, targetTeam.Name, newRootPosts[0].Id)
+	p.finishNonMemberParticipants(options.participants, nonMemberIDs, targetChannel, newPostLink)
+
+	msg := fmt.Sprintf("Messages have been %s: %s\n", pastTense(operation), newPostLink)
+	var pinnedCount int
+	for _, wpl := range wpls {
+		pinnedCount += wpl.PinnedPostCount
+	}
+	msg += fmt.Sprintf(
+		"\n| Team | Channel | Threads | Messages | Pinned Messages |\n| -- | -- | -- | -- | -- |\n| %s | %s | %d | %d | %d |\n\n",
+		targetTeam.DisplayName, targetChannel.DisplayName, len(wpls), postCount, pinnedCount,
+	)
+
+	audit.succeeded()
+
+	if operation == operationCopy {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+	}
+	msg += savedMessagesNotMoved
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, msg), false, nil
+}
+
+// getMessageRangeTimestamps returns the creation timestamps of the first and
+// last messages of a range. When no last message is given, the range has no
+// end.
+func (p *Plugin) getMessageRangeTimestamps(fromPostID, toPostID, channelID string) (int64, int64, error) {
+	fromPost, appErr := p.API.GetPost(fromPostID)
+	if appErr != nil || fromPost.ChannelId != channelID {
+		return 0, 0, fmt.Errorf("unable to get message with ID %s in this channel; ensure this is correct", fromPostID)
+	}
+	if len(toPostID) == 0 {
+		return fromPost.CreateAt, 0, nil
+	}
+
+	toPost, appErr := p.API.GetPost(toPostID)
+	if appErr != nil || toPost.ChannelId != channelID {
+		return 0, 0, fmt.Errorf("unable to get message with ID %s in this channel; ensure this is correct", toPostID)
+	}
+	if toPost.CreateAt < fromPost.CreateAt {
+		return 0, 0, errors.New("the --from message must be older than the --to message")
+	}
+
+	return fromPost.CreateAt, toPost.CreateAt, nil
+}
+
+func (p *Plugin) postMoveOrCopyMessagesBotDM(userID, operation, newPostLink string) error {
+	return p.PostBotDM(userID, fmt.Sprintf(
+		"Someone wrangled messages you posted to a new channel for you; they were %s here: %s", pastTense(operation), newPostLink,
+	))
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockGenerateChannelPosts returns a channel post list, newest first, with
+// three threads and a system message:
+//   - thread A: root at 1000 with replies at 1500 and 3000
+//   - thread B: root at 2000 with a reply at 2500
+//   - thread C: root at 4000
+func mockGenerateChannelPosts(channelID string) (*model.PostList, map[string]*model.Post) {
+	newPost := func(createAt int64, rootID string) *model.Post {
+		return &model.Post{
+			Id:        model.NewId(),
+			UserId:    model.NewId(),
+			ChannelId: channelID,
+			RootId:    rootID,
+			ParentId:  rootID,
+			CreateAt:  createAt,
+		}
+	}
+
+	posts := make(map[string]*model.Post)
+	posts["A"] = newPost(1000, "")
+	posts["A1"] = newPost(1500, posts["A"].Id)
+	posts["B"] = newPost(2000, "")
+	posts["system"] = newPost(2200, "")
+	posts["system"].Type = model.POST_JOIN_CHANNEL
+	posts["B1"] = newPost(2500, posts["B"].Id)
+	posts["A2"] = newPost(3000, posts["A"].Id)
+	posts["C"] = newPost(4000, "")
+
+	postList := model.NewPostList()
+	for _, post := range posts {
+		postList.AddPost(post)
+		postList.AddOrder(post.Id)
+	}
+	postList.SortByCreateAt()
+
+	return postList, posts
+}
+
+func TestBuildThreadWranglerPostLists(t *testing.T) {
+	postList, posts := mockGenerateChannelPosts(model.NewId())
+
+	t.Run("all threads", func(t *testing.T) {
+		wpls := buildThreadWranglerPostLists(postList, 0, 0)
+		require.Len(t, wpls, 3)
+		assert.Equal(t, []*model.Post{posts["A"], posts["A1"], posts["A2"]}, wpls[0].Posts)
+		assert.Equal(t, []*model.Post{posts["B"], posts["B1"]}, wpls[1].Posts)
+		assert.Equal(t, []*model.Post{posts["C"]}, wpls[2].Posts)
+	})
+
+	t.Run("range includes later replies", func(t *testing.T) {
+		wpls := buildThreadWranglerPostLists(postList, 1000, 2000)
+		require.Len(t, wpls, 2)
+		assert.Equal(t, 3, wpls[0].NumPosts())
+		assert.Equal(t, 2, wpls[1].NumPosts())
+	})
+
+	t.Run("range with no end", func(t *testing.T) {
+		wpls := buildThreadWranglerPostLists(postList, 1001, 0)
+		require.Len(t, wpls, 2)
+		assert.Equal(t, posts["B"], wpls[0].RootPost())
+		assert.Equal(t, posts["C"], wpls[1].RootPost())
+	})
+
+	t.Run("range with no root posts", func(t *testing.T) {
+		assert.Empty(t, buildThreadWranglerPostLists(postList, 2100, 2900))
+	})
+}
+
+func TestParseMoveOrCopyMessagesFlagArgs(t *testing.T) {
+	now := time.Now()
+
+	t.Run("message IDs", func(t *testing.T) {
+		options, err := parseMoveOrCopyMessagesFlagArgs(operationMove, []string{"--from", "id1", "--to", "id2", "channel1"}, false, now)
+		require.NoError(t, err)
+		assert.Equal(t, "channel1", options.channelID)
+		assert.Equal(t, "id1", options.fromPostID)
+		assert.Equal(t, "id2", options.toPostID)
+	})
+
+	t.Run("times", func(t *testing.T) {
+		_, err := parseMoveOrCopyMessagesFlagArgs(operationCopy, []string{"channel1", "--since", "2h", "--until", "2020-06-01T15:04:05Z"}, true, now)
+		require.Error(t, err)
+
+		options, err := parseMoveOrCopyMessagesFlagArgs(operationCopy, []string{"channel1", "--since", "2020-06-01T15:04:05Z", "--until", "2h"}, true, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1591023845000), options.since)
+		assert.Equal(t, model.GetMillisForTime(now.Add(-2*time.Hour)), options.until)
+		assert.True(t, options.preserveTimestamps)
+	})
+
+	t.Run("no range", func(t *testing.T) {
+		_, err := parseMoveOrCopyMessagesFlagArgs(operationMove, []string{"channel1"}, false, now)
+		require.Error(t, err)
+	})
+
+	t.Run("mixed range flags", func(t *testing.T) {
+		_, err := parseMoveOrCopyMessagesFlagArgs(operationMove, []string{"--from", "id1", "--until", "1h", "channel1"}, false, now)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid time", func(t *testing.T) {
+		_, err := parseMoveOrCopyMessagesFlagArgs(operationMove, []string{"--since", "yesterday", "channel1"}, false, now)
+		require.Error(t, err)
+	})
+
+	t.Run("missing channel", func(t *testing.T) {
+		_, err := parseMoveOrCopyMessagesFlagArgs(operationMove, []string{"--from", "id1"}, false, now)
+		require.Error(t, err)
+	})
+}
+
+func TestMoveMessagesCommand(t *testing.T) {
+	team1 := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	originalChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+		Name:   "original-channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+	targetChannel := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "target-channel",
+		DisplayName: "Target Channel",
+	}
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	// Only the user running the command is a member of otherChannel.
+	otherChannel := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "other-channel",
+		DisplayName: "Other Channel",
+	}
+
+	channelPosts, posts := mockGenerateChannelPosts(originalChannel.Id)
+	userID := posts["A"].UserId
+
+	api := &plugintest.API{}
+	api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+	api.On("GetChannel", targetChannel.Id).Return(targetChannel, nil)
+	api.On("GetChannel", otherChannel.Id).Return(otherChannel, nil)
+	api.On("GetChannelMember", targetChannel.Id, mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
+	api.On("GetChannelMember", otherChannel.Id, userID).Return(mockGenerateChannelMember(), nil)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), targetChannel.Id, model.PERMISSION_CREATE_POST).Return(true)
+	api.On("HasPermissionToChannel", userID, otherChannel.Id, model.PERMISSION_CREATE_POST).Return(true)
+	api.On("GetUser", mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetUser", "not.found", nil, "", 404))
+	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetChannelMember", "not.found", nil, "", 404))
+	api.On("GetPostsForChannel", originalChannel.Id, 0, channelPostsPerPage).Return(channelPosts, nil)
+	for _, post := range posts {
+		api.On("GetPost", post.Id).Return(post, nil)
+	}
+	api.On("GetPost", mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetPost", "not.found", nil, "", 404))
+	api.On("GetTeam", team1.Id).Return(team1, nil)
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.Channel{Id: model.NewId()}, nil)
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	api.On("GetConfig").Return(config)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("from message in another channel", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{"--from", model.NewId(), targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to get message with ID")
+	})
+
+	t.Run("from message after to message", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{"--from", posts["B"].Id, "--to", posts["A"].Id, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "the --from message must be older than the --to message")
+	})
+
+	t.Run("not a member of the target channel", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{"--from", posts["A"].Id, model.NewId()}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "doesn't exist or you are not a member")
+	})
+
+	t.Run("file limits apply to the whole range", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{FileReuploadMaxCount: "1", FileReuploadMaxSizeMB: "1"})
+		defer plugin.setConfiguration(&configuration{})
+		fileIDs := []string{model.NewId(), model.NewId()}
+		for _, fileID := range fileIDs {
+			api.On("GetFileInfo", fileID).Return(&model.FileInfo{Id: fileID, Name: "file.png", Size: 700 * 1024}, nil)
+		}
+		posts["A"].FileIds = model.StringArray{fileIDs[0]}
+		posts["B"].FileIds = model.StringArray{fileIDs[1]}
+		defer func() {
+			posts["A"].FileIds = nil
+			posts["B"].FileIds = nil
+		}()
+		createPostCalls := countMockCalls(&api.Mock, "CreatePost")
+
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{"--from", posts["A"].Id, "--to", posts["B"].Id, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the messages have 2 file attachments, but Wrangler is configured to only re-upload up to 1 files per command")
+		assert.Equal(t, createPostCalls, countMockCalls(&api.Mock, "CreatePost"))
+
+		plugin.setConfiguration(&configuration{FileReuploadMaxSizeMB: "1"})
+		resp, isUserError, err = plugin.runMoveMessagesCommand([]string{"--from", posts["A"].Id, "--to", posts["B"].Id, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "but Wrangler is configured to only re-upload up to 1.0 MB per command")
+		assert.Equal(t, createPostCalls, countMockCalls(&api.Mock, "CreatePost"))
+	})
+
+	t.Run("message limit applies to the whole range", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{MoveThreadMaxCount: "3"})
+		defer plugin.setConfiguration(&configuration{})
+
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{"--from", posts["A"].Id, "--to", posts["B"].Id, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the 2 threads are 5 posts long in total, but this command is configured to only move up to 3 posts")
+	})
+
+	t.Run("participants option refuses the move", func(t *testing.T) {
+		createPostCalls := countMockCalls(&api.Mock, "CreatePost")
+
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{"--from", posts["A"].Id, "--to", posts["A"].Id, "--participants", "block", otherChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "these participants are not members of the target channel")
+		assert.Equal(t, createPostCalls, countMockCalls(&api.Mock, "CreatePost"))
+	})
+
+	t.Run("move messages successfully", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMoveMessagesCommand([]string{"--from", posts["A"].Id, "--to", posts["B"].Id, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Equal(t, model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, resp.ResponseType)
+		assert.Contains(t, resp.Text, "Messages have been moved")
+		assert.Contains(t, resp.Text, "| Target Channel | 2 | 5 | 0 |")
+		assert.Contains(t, resp.Text, savedMessagesNotMoved)
+		api.AssertCalled(t, "DeletePost", posts["A"].Id)
+		api.AssertCalled(t, "DeletePost", posts["B"].Id)
+		api.AssertNotCalled(t, "DeletePost", posts["C"].Id)
+	})
+
+	t.Run("no messages in range", func(t *testing.T) {
+		resp, isUserError, err := plugin.runCopyMessagesCommand([]string{"--since", "2020-06-01T15:04:05Z", targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "no messages were found in the given range")
+	})
+
+	t.Run("copy messages successfully", func(t *testing.T) {
+		resp, isUserError, err := plugin.runCopyMessagesCommand([]string{"--since", "1970-01-01T00:00:03Z", targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, UserId: userID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Equal(t, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, resp.ResponseType)
+		assert.Contains(t, resp.Text, "Messages have been copied")
+		assert.Contains(t, resp.Text, "| Target Channel | 1 | 1 | 0 |")
+		assert.NotContains(t, resp.Text, savedMessagesNotMoved)
+		api.AssertNotCalled(t, "DeletePost", posts["C"].Id)
+	})
+}
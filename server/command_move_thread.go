@@ -17,23 +17,64 @@ const (
 %s`
 
 	flagMoveThreadShowMessageSummary = "show-root-message-in-summary"
+	flagPreserveTimestamps           = "preserve-timestamps"
+	flagSilent                       = "silent"
 )
 
+type moveThreadOptions struct {
+	showRootMessageInSummary bool
+	preserveTimestamps       bool
+	silent                   bool
+	dryRun                   bool
+	participants             string
+}
+
 func getMoveThreadFlagSet() *pflag.FlagSet {
 	flagSet := pflag.NewFlagSet("move thread", pflag.ContinueOnError)
 	flagSet.Bool(flagMoveThreadShowMessageSummary, true, "Show the root message in the post-move summary")
+	flagSet.Bool(flagPreserveTimestamps, false, "Keep the original timestamps of the moved messages (defaults to the plugin configuration)")
+	flagSet.Bool(flagSilent, true, "Don't notify users who are mentioned in the moved messages again by adding a zero-width space after each @ in the stored text")
+	flagSet.Bool(flagDryRun, false, "Report what the move would do without changing anything")
+	addParticipantsFlag(flagSet)
 
 	return flagSet
 }
 
-func parseMoveThreadFlagArgs(args []string) (bool, error) {
+func parseMoveThreadFlagArgs(args []string, preserveTimestampsDefault bool) (moveThreadOptions, error) {
+	var options moveThreadOptions
+
 	flagSet := getMoveThreadFlagSet()
 	err := flagSet.Parse(args)
 	if err != nil {
-		return false, errors.Wrap(err, "unable to parse move thread flag args")
+		return options, errors.Wrap(err, "unable to parse move thread flag args")
+	}
+
+	options.showRootMessageInSummary, err = flagSet.GetBool(flagMoveThreadShowMessageSummary)
+	if err != nil {
+		return options, err
+	}
+
+	options.preserveTimestamps, err = getBoolFlagWithDefault(flagSet, flagPreserveTimestamps, preserveTimestampsDefault)
+	if err != nil {
+		return options, err
+	}
+
+	options.silent, err = flagSet.GetBool(flagSilent)
+	if err != nil {
+		return options, err
+	}
+
+	options.dryRun, err = flagSet.GetBool(flagDryRun)
+	if err != nil {
+		return options, err
 	}
 
-	return flagSet.GetBool(flagMoveThreadShowMessageSummary)
+	options.participants, err = getParticipantsFlag(flagSet)
+	if err != nil {
+		return options, err
+	}
+
+	return options, nil
 }
 
 func getMoveThreadUsage() string {
@@ -45,12 +86,18 @@ func getMoveThreadMessage() string {
 }
 
 func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	return p.runMoveThread(args, extra, nil)
+}
+
+// runMoveThread moves a thread. The job is the background job running the
+// command, or nil if the command wasn't queued.
+func (p *Plugin) runMoveThread(args []string, extra *model.CommandArgs, job *WranglerJob) (*model.CommandResponse, bool, error) {
 	if len(args) < 2 {
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getMoveThreadMessage()), true, nil
 	}
-	showRootMessageInSummary, err := parseMoveThreadFlagArgs(args)
+	options, err := parseMoveThreadFlagArgs(args, p.getConfiguration().PreserveTimestampsByDefault)
 	if err != nil {
-		return nil, false, err
+		return nil, true, err
 	}
 	postID := args[0]
 	channelID := args[1]
@@ -74,11 +121,31 @@ func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (
 		return nil, false, fmt.Errorf("unable to get channel with ID %s", channelID)
 	}
 
-	response, userErr, err := p.validateMoveOrCopy(wpl, originalChannel, targetChannel, extra)
+	if options.dryRun {
+		blockers, err := p.getMoveOrCopyBlockers(operationMove, wpl, originalChannel, targetChannel, extra)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return p.runDryRun(operationMove, wpl, targetChannel, blockers, options.participants, extra.UserId)
+	}
+
+	response, userErr, err := p.validateMoveOrCopy(operationMove, wpl, originalChannel, targetChannel, extra)
 	if response != nil || err != nil {
 		return response, userErr, err
 	}
 
+	response, err = p.queueLargeJob(jobCommandMoveThread, job, wpl.NumPosts(), args, extra)
+	if response != nil || err != nil {
+		return response, false, err
+	}
+
+	nonMemberIDs, response, err := p.prepareNonMemberParticipants(options.participants, []*WranglerPostList{wpl}, targetChannel, extra.UserId)
+	if response != nil || err != nil {
+		// The participants option refusing the operation is a user error.
+		return response, response != nil, err
+	}
+
 	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
 	if appErr != nil {
 		return nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
@@ -91,14 +158,28 @@ func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (
 		"original_channel_id", originalChannel.Id,
 	)
 
+	audit := newAuditEntry(operationMove, extra.UserId, originalChannel.Id, targetChannel.Id, wpl.Posts)
+	defer p.recordAuditEntry(audit)
+
+	journal, err := p.startJournal(operationMove, extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
 	// To simulate the move, we first copy the original messages(s) to the
 	// new channel and later delete the original messages(s).
-	newRootPost, err := p.copyWranglerPostlist(wpl, targetChannel)
+	newRootPost, err := p.copyWranglerPostlist(wpl, targetChannel, copyOptions{
+		preserveTimestamps: options.preserveTimestamps,
+		silent:             options.silent,
+		operation:          operationMove,
+		userID:             extra.UserId,
+		originalTeamID:     originalChannel.TeamId,
+	}, journal)
 	if err != nil {
-		return nil, false, err
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
 	}
 
-	_, appErr = p.API.CreatePost(&model.Post{
+	botPost, appErr := p.API.CreatePost(&model.Post{
 		UserId:    p.BotUserID,
 		RootId:    newRootPost.Id,
 		ParentId:  newRootPost.Id,
@@ -106,15 +187,18 @@ func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (
 		Message:   "This thread was moved from another channel",
 	})
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to create new bot post")
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to create new bot post"))
+	}
+	err = p.journalPost(journal, botPost.Id)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
 	}
 
-	// Cleanup is handled by simply deleting the root post. Any comments/replies
-	// are automatically marked as deleted for us.
-	appErr = p.API.DeletePost(wpl.RootPost().Id)
-	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to delete post")
+	err = p.cleanupOriginalPosts(wpl, journal)
+	if err != nil {
+		return nil, false, err
 	}
+	p.saveUndoRecord(newThreadUndoRecord(operationMove, extra.UserId, originalChannel, targetChannel, wpl, journal))
 
 	p.API.LogInfo("Wrangler thread move complete",
 		"user_id", extra.UserId,
@@ -134,19 +218,23 @@ func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (
 			)
 		}
 	}
+	p.finishNonMemberParticipants(options.participants, nonMemberIDs, targetChannel, newPostLink)
 
 	msg := fmt.Sprintf("A thread has been moved: %s\n", newPostLink)
 	msg += fmt.Sprintf(
-		"\n| Team | Channel | Messages |\n| -- | -- | -- |\n| %s | %s | %d |\n\n",
-		targetTeam.DisplayName, targetChannel.DisplayName, wpl.NumPosts(),
+		"\n| Team | Channel | Messages | Pinned Messages |\n| -- | -- | -- | -- |\n| %s | %s | %d | %d |\n\n",
+		targetTeam.DisplayName, targetChannel.DisplayName, wpl.NumPosts(), wpl.PinnedPostCount,
 	)
-	if showRootMessageInSummary {
+	if options.showRootMessageInSummary {
 		msg += fmt.Sprintf("Original Thread Root Message:\n%s\n",
 			quoteBlock(cleanAndTrimMessage(
 				wpl.RootPost().Message, 500),
 			),
 		)
 	}
+	msg += savedMessagesNotMoved
+
+	audit.succeeded()
 
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, msg), false, nil
 }
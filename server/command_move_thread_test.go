@@ -77,6 +77,7 @@ func TestMoveThreadCommand(t *testing.T) {
 	api.On("GetChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(targetChannel, nil)
 	api.On("GetPostThread", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(generatedPosts, nil)
 	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(mockGenerateChannelMember(), nil)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
 	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(directChannel, nil)
 	api.On("GetTeam", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(targetTeam, nil)
 	api.On("CreatePost", mock.Anything, mock.Anything).Return(mockGeneratePost(), nil)
@@ -84,6 +85,10 @@ func TestMoveThreadCommand(t *testing.T) {
 	api.On("GetReactions", mock.AnythingOfType("string")).Return(reactions, nil)
 	api.On("AddReaction", mock.Anything).Return(nil, nil)
 	api.On("GetConfig", mock.Anything).Return(config)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
 	api.On("LogInfo",
 		mock.AnythingOfTypeArgument("string"),
 		mock.AnythingOfTypeArgument("string"),
@@ -249,9 +254,10 @@ func TestMoveThreadCommand(t *testing.T) {
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, fmt.Sprintf("A thread has been moved: %s", makePostLink(*config.ServiceSettings.SiteURL, targetTeam.Name, "")))
 		assert.Contains(t, resp.Text, fmt.Sprintf(
-			"\n| Team | Channel | Messages |\n| -- | -- | -- |\n| %s | %s | %d |\n\n",
-			targetTeam.DisplayName, targetChannel.DisplayName, 3,
+			"\n| Team | Channel | Messages | Pinned Messages |\n| -- | -- | -- | -- |\n| %s | %s | %d | %d |\n\n",
+			targetTeam.DisplayName, targetChannel.DisplayName, 3, 0,
 		))
+		assert.Contains(t, resp.Text, savedMessagesNotMoved)
 		assert.Contains(t, resp.Text, quoteBlock("This is message 1"))
 	})
 
@@ -263,9 +269,10 @@ func TestMoveThreadCommand(t *testing.T) {
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, fmt.Sprintf("A thread has been moved: %s", makePostLink(*config.ServiceSettings.SiteURL, targetTeam.Name, "")))
 		assert.Contains(t, resp.Text, fmt.Sprintf(
-			"\n| Team | Channel | Messages |\n| -- | -- | -- |\n| %s | %s | %d |\n\n",
-			targetTeam.DisplayName, targetChannel.DisplayName, 3,
+			"\n| Team | Channel | Messages | Pinned Messages |\n| -- | -- | -- | -- |\n| %s | %s | %d | %d |\n\n",
+			targetTeam.DisplayName, targetChannel.DisplayName, 3, 0,
 		))
+		assert.Contains(t, resp.Text, savedMessagesNotMoved)
 		assert.NotContains(t, resp.Text, "This is message 1")
 	})
 
@@ -277,6 +284,24 @@ func TestMoveThreadCommand(t *testing.T) {
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: the thread is 3 posts long, but this command is configured to only move threads of up to 1 posts")
 	})
+
+	t.Run("dry run", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{MoveThreadMaxCount: "1", MoveThreadToAnotherTeamEnable: false})
+		require.NoError(t, plugin.configuration.IsValid())
+		createPostCalls := countMockCalls(&api.Mock, "CreatePost")
+		deletePostCalls := countMockCalls(&api.Mock, "DeletePost")
+
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--dry-run"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Equal(t, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, resp.ResponseType)
+		assert.Contains(t, resp.Text, "Dry run: this is what the move would do")
+		assert.Contains(t, resp.Text, "| Target Channel | 3 | 0 | 0 B | 3 |")
+		assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving messages to different teams")
+		assert.Contains(t, resp.Text, "Error: the thread is 3 posts long")
+		assert.Equal(t, createPostCalls, countMockCalls(&api.Mock, "CreatePost"))
+		assert.Equal(t, deletePostCalls, countMockCalls(&api.Mock, "DeletePost"))
+	})
 }
 
 func TestSortedPostsFromPostList(t *testing.T) {
@@ -337,3 +362,52 @@ func mockGeneratePost() *model.Post {
 		Id: model.NewId(),
 	}
 }
+
+func TestParseMoveThreadFlagArgs(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		options, err := parseMoveThreadFlagArgs([]string{"id1", "id2"}, false)
+		require.NoError(t, err)
+		assert.True(t, options.showRootMessageInSummary)
+		assert.False(t, options.preserveTimestamps)
+		assert.True(t, options.silent)
+	})
+
+	t.Run("silent disabled", func(t *testing.T) {
+		options, err := parseMoveThreadFlagArgs([]string{"id1", "id2", "--silent=false"}, false)
+		require.NoError(t, err)
+		assert.False(t, options.silent)
+	})
+
+	t.Run("preserve timestamps from config default", func(t *testing.T) {
+		options, err := parseMoveThreadFlagArgs([]string{"id1", "id2"}, true)
+		require.NoError(t, err)
+		assert.True(t, options.preserveTimestamps)
+	})
+
+	t.Run("preserve timestamps flag overrides config default", func(t *testing.T) {
+		options, err := parseMoveThreadFlagArgs([]string{"id1", "id2", "--preserve-timestamps=false"}, true)
+		require.NoError(t, err)
+		assert.False(t, options.preserveTimestamps)
+
+		options, err = parseMoveThreadFlagArgs([]string{"id1", "id2", "--preserve-timestamps"}, false)
+		require.NoError(t, err)
+		assert.True(t, options.preserveTimestamps)
+	})
+
+	t.Run("participants", func(t *testing.T) {
+		options, err := parseMoveThreadFlagArgs([]string{"id1", "id2"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, participantsNotify, options.participants)
+
+		options, err = parseMoveThreadFlagArgs([]string{"id1", "id2", "--participants", "block"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, participantsBlock, options.participants)
+
+		_, err = parseMoveThreadFlagArgs([]string{"id1", "id2", "--participants", "ignore"}, false)
+		require.Error(t, err)
+	})
+	t.Run("job ID can't be set by users", func(t *testing.T) {
+		_, err := parseMoveThreadFlagArgs([]string{"id1", "id2", "--job-id=job1"}, false)
+		require.Error(t, err)
+	})
+}
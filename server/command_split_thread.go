@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const splitThreadUsage = `/wrangler split thread [REPLY_ID] [CHANNEL_ID]
+  Split a thread at a given reply, moving the reply and every later reply to a new thread
+    - The given reply becomes the root message of the new thread
+    - The new thread can be in the same channel or any channel in any team that you have joined
+    - Obtain the message ID via the 'Permalink' message dropdown option (it's the last part of the URL)
+    - Obtain the channel ID by running '/wrangler list channels' or via the channel 'View Info' option`
+
+func getSplitThreadMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", splitThreadUsage))
+}
+
+func (p *Plugin) runSplitThreadCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 2 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getSplitThreadMessage()), true, nil
+	}
+	postID := args[0]
+	channelID := args[1]
+
+	postListResponse, appErr := p.API.GetPostThread(postID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", postID)), true, nil
+	}
+	threadWPL := buildWranglerPostList(postListResponse)
+
+	splitPost, ok := postListResponse.Posts[postID]
+	if !ok {
+		return nil, false, fmt.Errorf("post with ID %s was not found in its own thread", postID)
+	}
+	if len(splitPost.RootId) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the message is the root of its thread; use '/wrangler move thread' to move the entire thread"), true, nil
+	}
+	wpl := threadWPL.SliceByTimestamp(splitPost.CreateAt, 0)
+
+	originalChannel, appErr := p.API.GetChannel(extra.ChannelId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
+	}
+	_, appErr = p.API.GetChannelMember(channelID, extra.UserId)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", channelID)), true, nil
+	}
+	targetChannel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", channelID)
+	}
+
+	response, userErr, err := p.validateMoveOrCopy(operationSplit, wpl, originalChannel, targetChannel, extra)
+	if response != nil || err != nil {
+		return response, userErr, err
+	}
+
+	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+	}
+
+	p.API.LogInfo("Wrangler is splitting a thread",
+		"user_id", extra.UserId,
+		"original_root_id", threadWPL.RootPost().Id,
+		"split_post_id", splitPost.Id,
+	)
+
+	audit := newAuditEntry(operationSplit, extra.UserId, originalChannel.Id, targetChannel.Id, wpl.Posts)
+	defer p.recordAuditEntry(audit)
+
+	journal, err := p.startJournal(operationSplit, extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	options := copyOptions{
+		preserveTimestamps: p.getConfiguration().PreserveTimestampsByDefault,
+		silent:             true,
+		operation:          operationSplit,
+		userID:             extra.UserId,
+		originalTeamID:     originalChannel.TeamId,
+	}
+	newRootPost, err := p.copyWranglerPostlist(wpl, targetChannel, options, journal)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	botPost, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		RootId:    newRootPost.Id,
+		ParentId:  newRootPost.Id,
+		ChannelId: targetChannel.Id,
+		Message:   "This thread was split from another thread",
+	})
+	if appErr != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to create new bot post"))
+	}
+	err = p.journalPost(journal, botPost.Id)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	newPostLink := makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, newRootPost.Id)
+	botPost, appErr = p.API.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		RootId:    threadWPL.RootPost().Id,
+		ParentId:  threadWPL.RootPost().Id,
+		ChannelId: originalChannel.Id,
+		Message:   fmt.Sprintf("%d messages from this thread were split into a new thread: %s", wpl.NumPosts(), newPostLink),
+	})
+	if appErr != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, errors.Wrap(appErr, "unable to create new bot post"))
+	}
+	err = p.journalPost(journal, botPost.Id)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	err = p.cleanupOriginalPosts(wpl, journal)
+	if err != nil {
+		return nil, false, err
+	}
+	p.clearUndoRecord(extra.UserId)
+
+	p.API.LogInfo("Wrangler thread split complete",
+		"user_id", extra.UserId,
+		"new_post_id", newRootPost.Id,
+		"new_channel_id", channelID,
+	)
+
+	if extra.UserId != splitPost.UserId {
+		// The new thread root was not written by the user running the command.
+		// Send a DM to the user who created it to let them know.
+		err := p.postSplitThreadBotDM(splitPost.UserId, newPostLink)
+		if err != nil {
+			p.API.LogError("Unable to send split-thread DM to user",
+				"error", err.Error(),
+				"user_id", splitPost.UserId,
+			)
+		}
+	}
+
+	msg := fmt.Sprintf("A thread has been split: %s\n", newPostLink)
+	msg += fmt.Sprintf(
+		"\n| Team | Channel | Messages |\n| -- | -- | -- |\n| %s | %s | %d |\n\n",
+		targetTeam.DisplayName, targetChannel.DisplayName, wpl.NumPosts(),
+	)
+
+	audit.succeeded()
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, msg), false, nil
+}
+
+func (p *Plugin) postSplitThreadBotDM(userID, newPostLink string) error {
+	return p.PostBotDM(userID, fmt.Sprintf(
+		"Someone wrangled one of your replies into a new thread for you: %s", newPostLink,
+	))
+}
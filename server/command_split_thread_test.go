@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitThreadCommand(t *testing.T) {
+	team1 := &model.Team{
+		Id:          model.NewId(),
+		Name:        "team-1",
+		DisplayName: "Team 1",
+	}
+	originalChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+		Name:   "original-channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+	targetChannel := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "target-channel",
+		DisplayName: "Target Channel",
+		Type:        model.CHANNEL_OPEN,
+	}
+
+	thread := mockGenerateThread(4, originalChannel.Id)
+	posts := thread.ToSlice()
+	rootPost := posts[len(posts)-1]
+	splitPost := posts[1]
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	api := &plugintest.API{}
+	api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+	api.On("GetChannel", targetChannel.Id).Return(targetChannel, nil)
+	api.On("GetPostThread", mock.AnythingOfType("string")).Return(thread, nil)
+	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.Channel{Id: model.NewId()}, nil)
+	api.On("GetTeam", mock.AnythingOfType("string")).Return(team1, nil)
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	api.On("GetConfig").Return(config)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{})
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("split at root", func(t *testing.T) {
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{rootPost.Id, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the message is the root of its thread")
+	})
+
+	t.Run("split thread successfully", func(t *testing.T) {
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{splitPost.Id, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "A thread has been split")
+		assert.Contains(t, resp.Text, fmt.Sprintf("| %s | %s | %d |", team1.DisplayName, targetChannel.DisplayName, 2))
+
+		// The remaining replies must be deleted individually so the original
+		// root is kept.
+		api.AssertCalled(t, "DeletePost", splitPost.Id)
+		api.AssertCalled(t, "DeletePost", posts[0].Id)
+		api.AssertNotCalled(t, "DeletePost", rootPost.Id)
+		api.AssertNotCalled(t, "DeletePost", posts[2].Id)
+	})
+}
+
+func TestSliceByTimestamp(t *testing.T) {
+	wpl := buildWranglerPostList(mockGenerateThread(5, model.NewId()))
+	require.Equal(t, 5, wpl.NumPosts())
+	require.True(t, wpl.IsFullThread())
+
+	t.Run("start only", func(t *testing.T) {
+		sliced := wpl.SliceByTimestamp(wpl.Posts[2].CreateAt, 0)
+		require.Equal(t, 3, sliced.NumPosts())
+		assert.Equal(t, wpl.Posts[2].Id, sliced.RootPost().Id)
+		assert.False(t, sliced.IsFullThread())
+		assert.Equal(t, wpl.Posts[2].CreateAt, sliced.EarlistPostTimestamp)
+		assert.Equal(t, wpl.Posts[4].CreateAt, sliced.LatestPostTimestamp)
+	})
+
+	t.Run("start and end", func(t *testing.T) {
+		sliced := wpl.SliceByTimestamp(wpl.Posts[1].CreateAt, wpl.Posts[2].CreateAt)
+		require.Equal(t, 2, sliced.NumPosts())
+		assert.Equal(t, wpl.Posts[1].Id, sliced.RootPost().Id)
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		sliced := wpl.SliceByTimestamp(wpl.LatestPostTimestamp+1, 0)
+		assert.Equal(t, 0, sliced.NumPosts())
+		assert.False(t, sliced.IsFullThread())
+	})
+}
+
+// mockGenerateThread generates a thread with a root post and replies that
+// each have a unique CreateAt.
+func mockGenerateThread(total int, channelID string) *model.PostList {
+	postList := model.NewPostList()
+	rootID := model.NewId()
+	createAt := model.GetMillis()
+	for i := 0; i < total; i++ {
+		post := &model.Post{
+			Id:        model.NewId(),
+			UserId:    model.NewId(),
+			ChannelId: channelID,
+			Message:   fmt.Sprintf("This is message %d", i+1),
+			CreateAt:  createAt + int64(i),
+		}
+		if i == 0 {
+			post.Id = rootID
+		} else {
+			post.RootId = rootID
+			post.ParentId = rootID
+		}
+		postList.AddPost(post)
+		postList.AddOrder(post.Id)
+	}
+	postList.SortByCreateAt()
+
+	return postList
+}
@@ -62,6 +62,13 @@ func TestCommand(t *testing.T) {
 				require.Nil(t, appErr)
 				require.Equal(t, resp.Text, getHelp())
 			})
+
+			t.Run("messages", func(t *testing.T) {
+				args := &model.CommandArgs{Command: "wrangler move messages"}
+				resp, appErr := plugin.ExecuteCommand(context, args)
+				require.Nil(t, appErr)
+				require.NotEqual(t, resp.Text, getHelp())
+			})
 		})
 
 		t.Run("copy command", func(t *testing.T) {
@@ -78,6 +85,13 @@ func TestCommand(t *testing.T) {
 				require.Nil(t, appErr)
 				require.Equal(t, resp.Text, getHelp())
 			})
+
+			t.Run("messages", func(t *testing.T) {
+				args := &model.CommandArgs{Command: "wrangler copy messages"}
+				resp, appErr := plugin.ExecuteCommand(context, args)
+				require.Nil(t, appErr)
+				require.NotEqual(t, resp.Text, getHelp())
+			})
 		})
 
 		t.Run("attach command", func(t *testing.T) {
@@ -232,4 +246,105 @@ func TestCommand(t *testing.T) {
 			})
 		})
 	})
+	t.Run("operation access level", func(t *testing.T) {
+		api.On("HasPermissionTo", commandUser.Id, model.PERMISSION_MANAGE_SYSTEM).Return(false)
+		plugin.setConfiguration(&configuration{
+			ListAccessLevel: accessLevelSystemAdmin,
+		})
+
+		t.Run("denied operation", func(t *testing.T) {
+			args := &model.CommandArgs{
+				UserId:  commandUser.Id,
+				Command: "wrangler list messages",
+			}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Equal(t, "Permission denied. Wrangler is configured to only allow system admins to list channels, messages and threads.", resp.Text)
+		})
+
+		t.Run("commands without an access level", func(t *testing.T) {
+			args := &model.CommandArgs{
+				UserId:  commandUser.Id,
+				Command: "wrangler info",
+			}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			infoResp, _, err := plugin.runInfoCommand([]string{}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, resp, infoResp)
+		})
+	})
+}
+
+func TestAuthorizedPluginUser(t *testing.T) {
+	employee := &model.User{Id: model.NewId(), Username: "employee", Email: "employee@company.com"}
+	contractor := &model.User{Id: model.NewId(), Username: "contractor", Email: "contractor@company.com"}
+	outsider := &model.User{Id: model.NewId(), Username: "outsider", Email: "outsider@elsewhere.com"}
+
+	api := &plugintest.API{}
+	for _, user := range []*model.User{employee, contractor, outsider} {
+		api.On("GetUser", user.Id).Return(user, nil)
+	}
+	api.On("GetGroupsForUser", employee.Id).Return([]*model.Group{{Name: NewString("staff"), DisplayName: "Staff"}}, nil)
+	api.On("GetGroupsForUser", contractor.Id).Return([]*model.Group{{DisplayName: "CN=Contractors"}}, nil)
+	api.On("GetGroupsForUser", outsider.Id).Return(nil, &model.AppError{Message: "not licensed"})
+	api.On("LogError", mock.AnythingOfTypeArgument("string"), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	tests := []struct {
+		name     string
+		config   *configuration
+		expected map[*model.User]bool
+	}{
+		{
+			name:     "no lists",
+			config:   &configuration{},
+			expected: map[*model.User]bool{employee: true, contractor: true, outsider: true},
+		},
+		{
+			name:     "allowed usernames",
+			config:   &configuration{AllowedUsernames: "@employee, outsider"},
+			expected: map[*model.User]bool{employee: true, contractor: false, outsider: true},
+		},
+		{
+			name:     "allowed usernames or email domain",
+			config:   &configuration{AllowedEmailDomain: "company.com", AllowedUsernames: "outsider"},
+			expected: map[*model.User]bool{employee: true, contractor: true, outsider: true},
+		},
+		{
+			name:     "denied username takes precedence over email domain",
+			config:   &configuration{AllowedEmailDomain: "company.com", DeniedUsernames: "Contractor"},
+			expected: map[*model.User]bool{employee: true, contractor: false, outsider: false},
+		},
+		{
+			name:     "denied username takes precedence over allowed username",
+			config:   &configuration{AllowedUsernames: "employee,contractor", DeniedUsernames: "contractor"},
+			expected: map[*model.User]bool{employee: true, contractor: false, outsider: false},
+		},
+		{
+			name:     "only denied usernames",
+			config:   &configuration{DeniedUsernames: "contractor"},
+			expected: map[*model.User]bool{employee: true, contractor: false, outsider: true},
+		},
+		{
+			name:     "allowed groups",
+			config:   &configuration{AllowedGroups: "staff"},
+			expected: map[*model.User]bool{employee: true, contractor: false, outsider: false},
+		},
+		{
+			name:     "denied group by display name takes precedence over email domain",
+			config:   &configuration{AllowedEmailDomain: "company.com", DeniedGroups: "cn=contractors"},
+			expected: map[*model.User]bool{employee: true, contractor: false, outsider: false},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plugin.setConfiguration(test.config)
+			for user, expected := range test.expected {
+				assert.Equal(t, expected, plugin.authorizedPluginUser(user.Id), user.Username)
+			}
+		})
+	}
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	traceUsage = `/wrangler trace [MESSAGE_ID]
+  Show where a message was wrangled from and where it has been wrangled to
+    - Obtain the message ID via the 'Permalink' message dropdown option (it's the last part of the URL)`
+
+	// maxTraceHops limits how far a trace follows provenance in each
+	// direction.
+	maxTraceHops = 50
+)
+
+func getTraceMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", traceUsage))
+}
+
+func (p *Plugin) runTraceCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getTraceMessage()), true, nil
+	}
+	postID := args[0]
+	notFoundResponse := getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postID))
+
+	backward, err := p.traceProvenanceBackward(postID)
+	if err != nil {
+		return nil, false, err
+	}
+	forward, err := p.traceProvenanceForward(postID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Messages that were moved no longer exist, so the channel they were in
+	// is taken from the provenance of the messages created from them.
+	channelIDs := make(map[string]string)
+	for _, pr := range append(backward, forward...) {
+		channelIDs[pr.OriginalPostID] = pr.OriginalChannelID
+	}
+	readable := make(map[string]bool)
+	if !p.canReadTracedPost(extra.UserId, postID, channelIDs, readable) {
+		return notFoundResponse, true, nil
+	}
+
+	// Only operations between channels that the user can read are shown.
+	var hidden int
+	backward, hidden = p.filterReadableProvenance(extra.UserId, backward, channelIDs, readable)
+	forward, forwardHidden := p.filterReadableProvenance(extra.UserId, forward, channelIDs, readable)
+	hidden += forwardHidden
+
+	if len(backward) == 0 && len(forward) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("No wrangler history was found for message %s", postID)), false, nil
+	}
+
+	usernames := make(map[string]string)
+	msg := fmt.Sprintf("Wrangler history for message %s\n", inlineCode(postID))
+	if len(backward) != 0 {
+		// Show the oldest operation first.
+		for i, j := 0, len(backward)-1; i < j; i, j = i+1, j-1 {
+			backward[i], backward[j] = backward[j], backward[i]
+		}
+		msg += "\n##### Wrangled from\n"
+		msg += p.formatProvenanceTable(backward, usernames)
+	}
+	if len(forward) != 0 {
+		msg += "\n##### Wrangled to\n"
+		msg += p.formatProvenanceTable(forward, usernames)
+	}
+	if hidden != 0 {
+		msg += fmt.Sprintf("\n%d operations involving channels you can't read are not shown\n", hidden)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+// traceProvenanceBackward follows the provenance of a post back to the post
+// it was originally created from. The newest operation is returned first.
+func (p *Plugin) traceProvenanceBackward(postID string) ([]*Provenance, error) {
+	var hops []*Provenance
+	visited := map[string]bool{postID: true}
+
+	for len(hops) < maxTraceHops {
+		pr, err := p.getProvenance(postID)
+		if err != nil {
+			return nil, err
+		}
+		if pr == nil {
+			break
+		}
+		pr.PostID = postID
+		hops = append(hops, pr)
+
+		if visited[pr.OriginalPostID] {
+			break
+		}
+		visited[pr.OriginalPostID] = true
+		postID = pr.OriginalPostID
+	}
+
+	return hops, nil
+}
+
+// traceProvenanceForward finds every post that was created from a post,
+// directly or through later operations, in the order they are reached.
+func (p *Plugin) traceProvenanceForward(postID string) ([]*Provenance, error) {
+	var hops []*Provenance
+	visited := map[string]bool{postID: true}
+	queue := []string{postID}
+
+	for len(queue) != 0 && len(hops) < maxTraceHops {
+		forwardIDs, err := p.getProvenanceForward(queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+
+		for _, forwardID := range forwardIDs {
+			if visited[forwardID] || len(hops) >= maxTraceHops {
+				continue
+			}
+			visited[forwardID] = true
+
+			pr, err := p.getProvenance(forwardID)
+			if err != nil {
+				return nil, err
+			}
+			if pr == nil {
+				continue
+			}
+			pr.PostID = forwardID
+			hops = append(hops, pr)
+			queue = append(queue, forwardID)
+		}
+	}
+
+	return hops, nil
+}
+
+// canReadTracedPost returns if the user can read the channel of a traced
+// post. The channel is looked up in the known channel IDs first, as the post
+// may no longer exist. Results are cached in readable by channel ID.
+func (p *Plugin) canReadTracedPost(userID, postID string, channelIDs map[string]string, readable map[string]bool) bool {
+	channelID, ok := channelIDs[postID]
+	if !ok {
+		post, appErr := p.API.GetPost(postID)
+		if appErr != nil {
+			return false
+		}
+		channelID = post.ChannelId
+		channelIDs[postID] = channelID
+	}
+
+	return p.canReadChannel(userID, channelID, readable)
+}
+
+func (p *Plugin) canReadChannel(userID, channelID string, readable map[string]bool) bool {
+	canRead, ok := readable[channelID]
+	if !ok {
+		canRead = p.API.HasPermissionToChannel(userID, channelID, model.PERMISSION_READ_CHANNEL)
+		readable[channelID] = canRead
+	}
+
+	return canRead
+}
+
+// filterReadableProvenance returns the provenance hops where the user can
+// read both the original channel and the channel of the new post, along with
+// the number of hops that were left out.
+func (p *Plugin) filterReadableProvenance(userID string, hops []*Provenance, channelIDs map[string]string, readable map[string]bool) ([]*Provenance, int) {
+	var filtered []*Provenance
+	for _, pr := range hops {
+		if !p.canReadChannel(userID, pr.OriginalChannelID, readable) ||
+			!p.canReadTracedPost(userID, pr.PostID, channelIDs, readable) {
+			continue
+		}
+		filtered = append(filtered, pr)
+	}
+
+	return filtered, len(hops) - len(filtered)
+}
+
+func (p *Plugin) formatProvenanceTable(hops []*Provenance, usernames map[string]string) string {
+	table := "| Message | Original Message | Original Channel | Operation | User | Time |\n| -- | -- | -- | -- | -- | -- |\n"
+	for _, pr := range hops {
+		table += fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+			pr.PostID,
+			pr.OriginalPostID,
+			pr.OriginalChannelID,
+			pr.Operation,
+			p.getCachedUsername(pr.UserID, usernames),
+			formatTimestamp(pr.CreateAt),
+		)
+	}
+
+	return table
+}
+
+func (p *Plugin) getCachedUsername(userID string, usernames map[string]string) string {
+	if username, ok := usernames[userID]; ok {
+		return username
+	}
+
+	username := userID
+	user, appErr := p.API.GetUser(userID)
+	if appErr == nil {
+		username = user.Username
+	}
+	usernames[userID] = username
+
+	return username
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceCommand(t *testing.T) {
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "wrangler-user",
+	}
+	channel1 := &model.Channel{Id: model.NewId()}
+	channel2 := &model.Channel{Id: model.NewId()}
+
+	// originalPostID was moved to movedPostID, which was then copied to
+	// copiedPostID. Only the copy still exists.
+	originalPostID := model.NewId()
+	movedPostID := model.NewId()
+	copiedPostID := model.NewId()
+	copiedPost := &model.Post{
+		Id:        copiedPostID,
+		ChannelId: channel2.Id,
+	}
+	untouchedPost := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: channel1.Id,
+	}
+
+	mustMarshal := func(v interface{}) []byte {
+		b, err := json.Marshal(v)
+		require.NoError(t, err)
+		return b
+	}
+
+	api := &plugintest.API{}
+	api.On("GetPost", copiedPostID).Return(copiedPost, nil)
+	api.On("GetPost", untouchedPost.Id).Return(untouchedPost, nil)
+	api.On("GetPost", mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetPost", "not.found", nil, "", 404))
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("HasPermissionToChannel", user.Id, mock.AnythingOfType("string"), model.PERMISSION_READ_CHANNEL).Return(true)
+	// limitedUserID can only read channel1.
+	limitedUserID := model.NewId()
+	api.On("HasPermissionToChannel", limitedUserID, channel1.Id, model.PERMISSION_READ_CHANNEL).Return(true)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), model.PERMISSION_READ_CHANNEL).Return(false)
+	api.On("KVGet", provenanceBackwardKeyPrefix+movedPostID).Return(mustMarshal(&Provenance{
+		PostID:            movedPostID,
+		OriginalPostID:    originalPostID,
+		OriginalChannelID: channel1.Id,
+		Operation:         operationMove,
+		UserID:            user.Id,
+	}), nil)
+	api.On("KVGet", provenanceBackwardKeyPrefix+copiedPostID).Return(mustMarshal(&Provenance{
+		PostID:            copiedPostID,
+		OriginalPostID:    movedPostID,
+		OriginalChannelID: channel2.Id,
+		Operation:         operationCopy,
+		UserID:            user.Id,
+	}), nil)
+	api.On("KVGet", provenanceForwardKeyPrefix+originalPostID).Return(mustMarshal([]string{movedPostID}), nil)
+	api.On("KVGet", provenanceForwardKeyPrefix+movedPostID).Return(mustMarshal([]string{copiedPostID}), nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runTraceCommand([]string{}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("no permission to read channel", func(t *testing.T) {
+		resp, isUserError, err := plugin.runTraceCommand([]string{copiedPostID}, &model.CommandArgs{UserId: model.NewId()})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to get message with ID")
+	})
+
+	t.Run("no permission to read channel of moved message", func(t *testing.T) {
+		resp, isUserError, err := plugin.runTraceCommand([]string{movedPostID}, &model.CommandArgs{UserId: limitedUserID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to get message with ID")
+	})
+
+	t.Run("operations in unreadable channels are hidden", func(t *testing.T) {
+		resp, isUserError, err := plugin.runTraceCommand([]string{originalPostID}, &model.CommandArgs{UserId: limitedUserID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "No wrangler history was found")
+		assert.NotContains(t, resp.Text, movedPostID)
+		assert.NotContains(t, resp.Text, copiedPostID)
+	})
+
+	t.Run("no history", func(t *testing.T) {
+		resp, isUserError, err := plugin.runTraceCommand([]string{untouchedPost.Id}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "No wrangler history was found")
+	})
+
+	t.Run("trace backward", func(t *testing.T) {
+		hops, err := plugin.traceProvenanceBackward(copiedPostID)
+		require.NoError(t, err)
+		require.Len(t, hops, 2)
+		assert.Equal(t, movedPostID, hops[0].OriginalPostID)
+		assert.Equal(t, originalPostID, hops[1].OriginalPostID)
+	})
+
+	t.Run("trace forward", func(t *testing.T) {
+		hops, err := plugin.traceProvenanceForward(originalPostID)
+		require.NoError(t, err)
+		require.Len(t, hops, 2)
+		assert.Equal(t, movedPostID, hops[0].PostID)
+		assert.Equal(t, copiedPostID, hops[1].PostID)
+	})
+
+	t.Run("trace from the middle of a chain", func(t *testing.T) {
+		resp, isUserError, err := plugin.runTraceCommand([]string{movedPostID}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Wrangled from")
+		assert.Contains(t, resp.Text, "Wrangled to")
+		assert.Contains(t, resp.Text, originalPostID)
+		assert.Contains(t, resp.Text, copiedPostID)
+		assert.Contains(t, resp.Text, user.Username)
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const undoUsage = `/wrangler undo
+  Undo your most recent move thread, copy thread or attach message command
+    - Moved threads are recreated in their original channel, copied threads are deleted and attached messages are detached again
+    - Commands can only be undone for a short time after they are run`
+
+func (p *Plugin) runUndoCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	record, err := p.takeUndoRecord(extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+	if record == nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: you have no recent move, copy or attach to undo"), true, nil
+	}
+
+	window := p.getConfiguration().UndoWindow()
+	if record.isExpired(model.GetMillis(), window) {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf(
+			"Error: your last %s was more than %d minutes ago and can no longer be undone", record.Operation, int(window.Minutes()),
+		)), true, nil
+	}
+
+	var response *model.CommandResponse
+	var userErr bool
+	switch record.Operation {
+	case operationMove:
+		response, userErr, err = p.undoMove(record, extra)
+	case operationCopy:
+		response, userErr, err = p.undoCopy(record)
+	case operationAttach:
+		response, userErr, err = p.undoAttach(record)
+	default:
+		err = errors.Errorf("unable to undo %s operations", record.Operation)
+	}
+	if userErr {
+		return response, userErr, err
+	}
+
+	// The undo takes the posts created by the operation back out of the
+	// channel they were wrangled to.
+	audit := newAuditEntry(operationUndo, extra.UserId, record.TargetChannelID, record.OriginalChannelID, nil)
+	audit.PostIDs = record.NewPostIDs
+	audit.PostCount = len(record.NewPostIDs)
+	if err == nil {
+		audit.succeeded()
+	}
+	p.recordAuditEntry(audit)
+
+	if err != nil {
+		// Keep the record so that the undo can be tried again.
+		p.saveUndoRecord(record)
+		return nil, false, err
+	}
+
+	p.API.LogInfo("Wrangler has undone an operation",
+		"user_id", extra.UserId,
+		"operation", record.Operation,
+		"new_root_post_id", record.NewRootPostID,
+	)
+
+	return response, false, nil
+}
+
+// undoMove moves a thread back to the channel it was moved from, with the
+// original timestamps of its messages.
+func (p *Plugin) undoMove(record *UndoRecord, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	postList, appErr := p.API.GetPostThread(record.NewRootPostID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the moved thread no longer exists"), true, nil
+	}
+	_, appErr = p.API.GetChannelMember(record.OriginalChannelID, record.UserID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the original channel no longer exists or you are no longer a member"), true, nil
+	}
+	originalChannel, appErr := p.API.GetChannel(record.OriginalChannelID)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", record.OriginalChannelID)
+	}
+	if originalChannel.DeleteAt != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the original channel has been archived"), true, nil
+	}
+	targetChannel, appErr := p.API.GetChannel(record.TargetChannelID)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", record.TargetChannelID)
+	}
+
+	var posts []*model.Post
+	for _, post := range buildWranglerPostList(postList).Posts {
+		createAt, ok := record.OriginalCreateAts[post.Id]
+		if !ok && post.UserId == p.BotUserID {
+			// Leave out the note that the bot added to the moved thread.
+			continue
+		}
+		if ok {
+			post.CreateAt = createAt
+		}
+		posts = append(posts, post)
+	}
+	wpl := newWranglerPostListFromPosts(posts)
+
+	journal, err := p.startJournal(operationUndo, record.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	newRootPost, err := p.copyWranglerPostlist(wpl, originalChannel, copyOptions{
+		preserveTimestamps: true,
+		silent:             true,
+		operation:          operationUndo,
+		userID:             record.UserID,
+		originalTeamID:     targetChannel.TeamId,
+	}, journal)
+	if err != nil {
+		return nil, false, p.rollbackJournalAndWrap(journal, err)
+	}
+
+	err = p.cleanupOriginalPosts(wpl, journal)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Direct and group message channels don't belong to a team, so link to
+	// them through the team the command was run in.
+	teamID := originalChannel.TeamId
+	if len(teamID) == 0 {
+		teamID = extra.TeamId
+	}
+	team, appErr := p.API.GetTeam(teamID)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get team with ID %s", teamID)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf(
+		"The move has been undone and the thread is back in its original channel: %s",
+		makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, team.Name, newRootPost.Id),
+	)), false, nil
+}
+
+// undoCopy deletes the posts created by a copy.
+func (p *Plugin) undoCopy(record *UndoRecord) (*model.CommandResponse, bool, error) {
+	// Delete in reverse order so that replies are removed before their root.
+	for i := len(record.NewPostIDs) - 1; i >= 0; i-- {
+		appErr := p.API.DeletePost(record.NewPostIDs[i])
+		if appErr != nil && appErr.StatusCode != http.StatusNotFound {
+			return nil, false, errors.Wrap(appErr, "unable to delete copied post")
+		}
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "The copy has been undone and the copied messages have been deleted"), false, nil
+}
+
+// undoAttach turns an attached reply back into a top-level message.
+func (p *Plugin) undoAttach(record *UndoRecord) (*model.CommandResponse, bool, error) {
+	post, appErr := p.API.GetPost(record.NewRootPostID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the attached message no longer exists"), true, nil
+	}
+	if len(post.RootId) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the attached message is no longer part of a thread"), true, nil
+	}
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		return nil, false, fmt.Errorf("unable to get channel with ID %s", post.ChannelId)
+	}
+
+	_, err := p.detachPost(post, nil, operationUndo, record.UserID, channel.TeamId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "The attach has been undone and the message is no longer part of the thread"), false, nil
+}
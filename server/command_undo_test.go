@@ -0,0 +1,239 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoCommand(t *testing.T) {
+	userID := model.NewId()
+	team1 := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	originalChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+	}
+	targetChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+	}
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		mockKVStore(api)
+		api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+		api.On("GetChannel", targetChannel.Id).Return(targetChannel, nil)
+		api.On("GetTeam", team1.Id).Return(team1, nil)
+		api.On("GetConfig").Return(config)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+		api.On("LogInfo",
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+		).Return(nil)
+
+		return api
+	}
+
+	t.Run("nothing to undo", func(t *testing.T) {
+		var plugin Plugin
+		plugin.SetAPI(setupAPI())
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "you have no recent move, copy or attach to undo")
+	})
+
+	t.Run("outside the undo window", func(t *testing.T) {
+		var plugin Plugin
+		plugin.SetAPI(setupAPI())
+		plugin.setConfiguration(&configuration{UndoWindowMinutes: "5"})
+
+		plugin.saveUndoRecord(&UndoRecord{
+			Operation: operationCopy,
+			UserID:    userID,
+			CreateAt:  model.GetMillisForTime(time.Now().Add(-6 * time.Minute)),
+		})
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "your last copy was more than 5 minutes ago")
+	})
+
+	t.Run("undo copy", func(t *testing.T) {
+		api := setupAPI()
+		var deleted []string
+		api.On("DeletePost", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+			deleted = append(deleted, args.String(0))
+		})
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		plugin.saveUndoRecord(&UndoRecord{
+			Operation:     operationCopy,
+			UserID:        userID,
+			CreateAt:      model.GetMillis(),
+			NewRootPostID: "new1",
+			NewPostIDs:    []string{"new1", "new2", "bot1"},
+		})
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "The copy has been undone")
+		assert.Equal(t, []string{"bot1", "new2", "new1"}, deleted)
+
+		resp, isUserError, err = plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "you have no recent move, copy or attach to undo")
+	})
+
+	t.Run("operations that can't be undone replace the undo record", func(t *testing.T) {
+		rootPost := &model.Post{Id: model.NewId(), UserId: userID, ChannelId: targetChannel.Id}
+		reply := &model.Post{Id: model.NewId(), UserId: userID, ChannelId: targetChannel.Id, RootId: rootPost.Id, ParentId: rootPost.Id}
+
+		api := setupAPI()
+		api.On("GetPost", reply.Id).Return(reply, nil)
+		api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+		api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		plugin.saveUndoRecord(&UndoRecord{
+			Operation:  operationCopy,
+			UserID:     userID,
+			CreateAt:   model.GetMillis(),
+			NewPostIDs: []string{"new1"},
+		})
+
+		_, _, err := plugin.runDetachMessageCommand([]string{reply.Id}, &model.CommandArgs{UserId: userID, ChannelId: targetChannel.Id, TeamId: team1.Id})
+		require.NoError(t, err)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "you have no recent move, copy or attach to undo")
+		api.AssertNotCalled(t, "DeletePost", "new1")
+	})
+
+	t.Run("failed undo can be retried", func(t *testing.T) {
+		api := setupAPI()
+		api.On("DeletePost", mock.AnythingOfType("string")).Return(model.NewAppError("DeletePost", "failed", nil, "", http.StatusInternalServerError))
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		plugin.saveUndoRecord(&UndoRecord{
+			Operation:  operationCopy,
+			UserID:     userID,
+			CreateAt:   model.GetMillis(),
+			NewPostIDs: []string{"new1"},
+		})
+
+		_, _, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID})
+		require.Error(t, err)
+
+		record, err := plugin.takeUndoRecord(userID)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, []string{"new1"}, record.NewPostIDs)
+	})
+
+	t.Run("undo move", func(t *testing.T) {
+		movedRoot := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: targetChannel.Id, CreateAt: 5000}
+		movedReply := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: targetChannel.Id, RootId: movedRoot.Id, ParentId: movedRoot.Id, CreateAt: 5001}
+		botPost := &model.Post{Id: model.NewId(), ChannelId: targetChannel.Id, RootId: movedRoot.Id, ParentId: movedRoot.Id, CreateAt: 5002}
+		postList := model.NewPostList()
+		for _, post := range []*model.Post{movedRoot, movedReply, botPost} {
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+
+		api := setupAPI()
+		api.On("GetPostThread", movedRoot.Id).Return(postList, nil)
+		api.On("GetChannelMember", originalChannel.Id, userID).Return(mockGenerateChannelMember(), nil)
+		var created []*model.Post
+		api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+			created = append(created, post)
+			newPost := post.Clone()
+			newPost.Id = model.NewId()
+			return newPost
+		}, nil)
+		api.On("DeletePost", movedRoot.Id).Return(nil)
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+		plugin.BotUserID = model.NewId()
+		botPost.UserId = plugin.BotUserID
+
+		originalPosts := newWranglerPostListFromPosts([]*model.Post{
+			{Id: model.NewId(), CreateAt: 1000},
+			{Id: model.NewId(), CreateAt: 1001},
+		})
+		journal := &WranglerJournal{PostIDs: []string{movedRoot.Id, movedReply.Id, botPost.Id}}
+		plugin.saveUndoRecord(newThreadUndoRecord(operationMove, userID, originalChannel, targetChannel, originalPosts, journal))
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID, TeamId: team1.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "The move has been undone")
+
+		require.Len(t, created, 2)
+		assert.Equal(t, originalChannel.Id, created[0].ChannelId)
+		assert.Equal(t, int64(1000), created[0].CreateAt)
+		assert.Equal(t, int64(1001), created[1].CreateAt)
+		api.AssertCalled(t, "DeletePost", movedRoot.Id)
+	})
+
+	t.Run("undo attach", func(t *testing.T) {
+		attached := &model.Post{Id: model.NewId(), UserId: model.NewId(), ChannelId: originalChannel.Id, RootId: model.NewId(), ParentId: model.NewId(), CreateAt: 1000}
+
+		api := setupAPI()
+		api.On("GetPost", attached.Id).Return(attached, nil)
+		api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+		api.On("DeletePost", attached.Id).Return(nil)
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		plugin.saveUndoRecord(&UndoRecord{
+			Operation:     operationAttach,
+			UserID:        userID,
+			CreateAt:      model.GetMillis(),
+			NewRootPostID: attached.Id,
+			NewPostIDs:    []string{attached.Id},
+		})
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: userID})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "The attach has been undone")
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "" && post.CreateAt == 1000
+		}))
+		api.AssertCalled(t, "DeletePost", attached.Id)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -23,6 +24,10 @@ import (
 // copy appropriate for your types.
 type configuration struct {
 	AllowedEmailDomain        string
+	AllowedUsernames          string
+	DeniedUsernames           string
+	AllowedGroups             string
+	DeniedGroups              string
 	EnableWebUI               bool
 	CommandAutoCompleteEnable bool
 
@@ -31,6 +36,25 @@ type configuration struct {
 	MoveThreadFromPrivateChannelEnable       bool
 	MoveThreadFromDirectMessageChannelEnable bool
 	MoveThreadFromGroupMessageChannelEnable  bool
+	PreserveTimestampsByDefault              bool
+	MoveOthersPostsRequirePermission         bool
+
+	BackgroundJobThreshold string
+	UndoWindowMinutes      string
+	FileReuploadMaxCount   string
+	FileReuploadMaxSizeMB  string
+
+	MoveAccessLevel   string
+	CopyAccessLevel   string
+	AttachAccessLevel string
+	ListAccessLevel   string
+
+	MoveRateLimitPerUser   string
+	MoveRateLimitGlobal    string
+	CopyRateLimitPerUser   string
+	CopyRateLimitGlobal    string
+	AttachRateLimitPerUser string
+	AttachRateLimitGlobal  string
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -56,11 +80,76 @@ func (c *configuration) IsValid() error {
 		}
 	}
 
+	nameLists := []struct {
+		name  string
+		value string
+	}{
+		{"AllowedUsernames", c.AllowedUsernames},
+		{"DeniedUsernames", c.DeniedUsernames},
+		{"AllowedGroups", c.AllowedGroups},
+		{"DeniedGroups", c.DeniedGroups},
+	}
+	for _, list := range nameLists {
+		if len(strings.TrimSpace(list.value)) == 0 {
+			continue
+		}
+		for _, name := range strings.Split(list.value, ",") {
+			if len(strings.TrimSpace(name)) == 0 {
+				return errors.Errorf("%s has an empty entry", list.name)
+			}
+		}
+	}
+
 	_, err = parseAndValidateMaxThreadCountMoveSize(c.MoveThreadMaxCount)
 	if err != nil {
 		return errors.Wrap(err, "invalid MoveThreadMaxSize")
 	}
 
+	_, err = parseAndValidateOptionalPositiveInt("BackgroundJobThreshold", c.BackgroundJobThreshold)
+	if err != nil {
+		return errors.Wrap(err, "invalid BackgroundJobThreshold")
+	}
+
+	_, err = parseAndValidateOptionalPositiveInt("UndoWindowMinutes", c.UndoWindowMinutes)
+	if err != nil {
+		return errors.Wrap(err, "invalid UndoWindowMinutes")
+	}
+
+	_, err = parseAndValidateOptionalPositiveInt("FileReuploadMaxCount", c.FileReuploadMaxCount)
+	if err != nil {
+		return errors.Wrap(err, "invalid FileReuploadMaxCount")
+	}
+
+	_, err = parseAndValidateOptionalPositiveInt("FileReuploadMaxSizeMB", c.FileReuploadMaxSizeMB)
+	if err != nil {
+		return errors.Wrap(err, "invalid FileReuploadMaxSizeMB")
+	}
+
+	rateLimits := []struct {
+		name  string
+		value string
+	}{
+		{"MoveRateLimitPerUser", c.MoveRateLimitPerUser},
+		{"MoveRateLimitGlobal", c.MoveRateLimitGlobal},
+		{"CopyRateLimitPerUser", c.CopyRateLimitPerUser},
+		{"CopyRateLimitGlobal", c.CopyRateLimitGlobal},
+		{"AttachRateLimitPerUser", c.AttachRateLimitPerUser},
+		{"AttachRateLimitGlobal", c.AttachRateLimitGlobal},
+	}
+	for _, rateLimit := range rateLimits {
+		_, err = parseAndValidateOptionalPositiveInt(rateLimit.name, rateLimit.value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s", rateLimit.name)
+		}
+	}
+
+	for _, operation := range accessOperations {
+		level := c.AccessLevel(operation)
+		if !isValidAccessLevel(level) {
+			return errors.Errorf("invalid access level %s for %s operations", level, operation)
+		}
+	}
+
 	return nil
 }
 
@@ -71,24 +160,107 @@ func (c *configuration) MaxThreadCountMoveSizeInt() int {
 	return i
 }
 
+// BackgroundJobThresholdInt returns the number of messages an operation must
+// exceed to be queued as a background job, or 0 if operations are never
+// queued.
+func (c *configuration) BackgroundJobThresholdInt() int {
+	i, _ := parseAndValidateOptionalPositiveInt("BackgroundJobThreshold", c.BackgroundJobThreshold)
+
+	return i
+}
+
+// UndoWindow returns how long after an operation it can be undone. When
+// UndoWindowMinutes is not configured, defaultUndoWindow is used.
+func (c *configuration) UndoWindow() time.Duration {
+	minutes, _ := parseAndValidateOptionalPositiveInt("UndoWindowMinutes", c.UndoWindowMinutes)
+	if minutes == 0 {
+		return defaultUndoWindow
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
+
+// FileReuploadMaxCountInt returns the maximum number of files a single command
+// can re-upload, or 0 if the number is unlimited.
+func (c *configuration) FileReuploadMaxCountInt() int {
+	i, _ := parseAndValidateOptionalPositiveInt("FileReuploadMaxCount", c.FileReuploadMaxCount)
+
+	return i
+}
+
+// FileReuploadMaxBytes returns the maximum total size of the files a single
+// command can re-upload, or 0 if the size is unlimited.
+func (c *configuration) FileReuploadMaxBytes() int64 {
+	mb, _ := parseAndValidateOptionalPositiveInt("FileReuploadMaxSizeMB", c.FileReuploadMaxSizeMB)
+
+	return int64(mb) * 1024 * 1024
+}
+
+// AccessLevel returns the access level required for an operation. Operations
+// without a configured access level are allowed for everyone.
+func (c *configuration) AccessLevel(operation string) string {
+	var level string
+	switch operation {
+	case operationMove:
+		level = c.MoveAccessLevel
+	case operationCopy:
+		level = c.CopyAccessLevel
+	case operationAttach:
+		level = c.AttachAccessLevel
+	case operationList:
+		level = c.ListAccessLevel
+	}
+	if len(level) == 0 {
+		return accessLevelEveryone
+	}
+
+	return level
+}
+
+// RateLimits returns how many times per hour an operation can be run by each
+// user and by all users together. A limit of 0 means the operation is not
+// limited.
+func (c *configuration) RateLimits(operation string) (int, int) {
+	var perUser, global string
+	switch operation {
+	case operationMove:
+		perUser, global = c.MoveRateLimitPerUser, c.MoveRateLimitGlobal
+	case operationCopy:
+		perUser, global = c.CopyRateLimitPerUser, c.CopyRateLimitGlobal
+	case operationAttach:
+		perUser, global = c.AttachRateLimitPerUser, c.AttachRateLimitGlobal
+	}
+
+	perUserLimit, _ := parseAndValidateOptionalPositiveInt("", perUser)
+	globalLimit, _ := parseAndValidateOptionalPositiveInt("", global)
+
+	return perUserLimit, globalLimit
+}
+
 // parseAndValidateMaxThreadCountMoveSize parses the max thread size config
 // value and returns an error if the value is invalid or cannot be parsed.
 // If MaxThreadCountMoveSize is not configured, set it to 0 which stands for
 // unlimited thread message count.
 func parseAndValidateMaxThreadCountMoveSize(s string) (int, error) {
+	return parseAndValidateOptionalPositiveInt("MoveThreadMaxSize", s)
+}
+
+// parseAndValidateOptionalPositiveInt parses an optional integer config value
+// that must be greater than 0 when set. An empty value is returned as 0.
+func parseAndValidateOptionalPositiveInt(name, s string) (int, error) {
 	if len(s) == 0 {
 		return 0, nil
 	}
 
-	max, err := strconv.Atoi(s)
+	i, err := strconv.Atoi(s)
 	if err != nil {
-		return 0, errors.Wrapf(err, "MoveThreadMaxSize value %s is not a valid integer", s)
+		return 0, errors.Wrapf(err, "%s value %s is not a valid integer", name, s)
 	}
-	if max < 1 {
-		return 0, fmt.Errorf("MoveThreadMaxSize (%d) must be greater than 0", max)
+	if i < 1 {
+		return 0, fmt.Errorf("%s (%d) must be greater than 0", name, i)
 	}
 
-	return max, nil
+	return i, nil
 }
 
 // getConfiguration retrieves the active configuration under lock, making it safe to use
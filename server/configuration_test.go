@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -60,4 +61,144 @@ func TestConfigurationIsValid(t *testing.T) {
 			require.NoError(t, config.IsValid())
 		})
 	})
+
+	t.Run("BackgroundJobThreshold", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("invalid integer", func(t *testing.T) {
+			config.BackgroundJobThreshold = "lots"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("zero", func(t *testing.T) {
+			config.BackgroundJobThreshold = "0"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("valid value", func(t *testing.T) {
+			config.BackgroundJobThreshold = "500"
+			require.NoError(t, config.IsValid())
+			require.Equal(t, 500, config.BackgroundJobThresholdInt())
+		})
+
+		t.Run("unset value", func(t *testing.T) {
+			config.BackgroundJobThreshold = ""
+			require.NoError(t, config.IsValid())
+			require.Equal(t, 0, config.BackgroundJobThresholdInt())
+		})
+	})
+	t.Run("UndoWindowMinutes", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("invalid integer", func(t *testing.T) {
+			config.UndoWindowMinutes = "ten"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("valid value", func(t *testing.T) {
+			config.UndoWindowMinutes = "30"
+			require.NoError(t, config.IsValid())
+			require.Equal(t, 30*time.Minute, config.UndoWindow())
+		})
+
+		t.Run("unset value", func(t *testing.T) {
+			config.UndoWindowMinutes = ""
+			require.NoError(t, config.IsValid())
+			require.Equal(t, defaultUndoWindow, config.UndoWindow())
+		})
+	})
+	t.Run("FileReuploadLimits", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("invalid count", func(t *testing.T) {
+			config.FileReuploadMaxCount = "-1"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("invalid size", func(t *testing.T) {
+			config.FileReuploadMaxCount = ""
+			config.FileReuploadMaxSizeMB = "lots"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("valid values", func(t *testing.T) {
+			config.FileReuploadMaxCount = "20"
+			config.FileReuploadMaxSizeMB = "50"
+			require.NoError(t, config.IsValid())
+			require.Equal(t, 20, config.FileReuploadMaxCountInt())
+			require.Equal(t, int64(50*1024*1024), config.FileReuploadMaxBytes())
+		})
+
+		t.Run("unset values", func(t *testing.T) {
+			config.FileReuploadMaxCount = ""
+			config.FileReuploadMaxSizeMB = ""
+			require.NoError(t, config.IsValid())
+			require.Equal(t, 0, config.FileReuploadMaxCountInt())
+			require.Equal(t, int64(0), config.FileReuploadMaxBytes())
+		})
+	})
+	t.Run("AccessLevel", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("unset value", func(t *testing.T) {
+			config.MoveAccessLevel = ""
+			require.NoError(t, config.IsValid())
+			require.Equal(t, accessLevelEveryone, config.AccessLevel(operationMove))
+		})
+
+		t.Run("valid value", func(t *testing.T) {
+			config.ListAccessLevel = accessLevelChannelAdmin
+			require.NoError(t, config.IsValid())
+			require.Equal(t, accessLevelChannelAdmin, config.AccessLevel(operationList))
+		})
+
+		t.Run("invalid value", func(t *testing.T) {
+			config.CopyAccessLevel = "team_member"
+			require.Error(t, config.IsValid())
+		})
+	})
+	t.Run("user and group lists", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("valid lists", func(t *testing.T) {
+			config.AllowedUsernames = "user1, @user2"
+			config.DeniedGroups = "contractors"
+			require.NoError(t, config.IsValid())
+		})
+
+		t.Run("empty entry", func(t *testing.T) {
+			config.DeniedUsernames = "user3,,user4"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("trailing comma", func(t *testing.T) {
+			config.DeniedUsernames = ""
+			config.AllowedGroups = "staff,"
+			require.Error(t, config.IsValid())
+		})
+	})
+	t.Run("RateLimits", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("unset values", func(t *testing.T) {
+			require.NoError(t, config.IsValid())
+			perUser, global := config.RateLimits(operationMove)
+			require.Equal(t, 0, perUser)
+			require.Equal(t, 0, global)
+		})
+
+		t.Run("valid values", func(t *testing.T) {
+			config.CopyRateLimitPerUser = "20"
+			config.CopyRateLimitGlobal = "100"
+			require.NoError(t, config.IsValid())
+			perUser, global := config.RateLimits(operationCopy)
+			require.Equal(t, 20, perUser)
+			require.Equal(t, 100, global)
+		})
+
+		t.Run("invalid value", func(t *testing.T) {
+			config.AttachRateLimitGlobal = "0"
+			require.Error(t, config.IsValid())
+		})
+	})
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const flagDryRun = "dry-run"
+
+// isDryRun returns if the command arguments ask for a dry run. The other flags
+// of the command are ignored.
+func isDryRun(args []string) bool {
+	flagSet := pflag.NewFlagSet("dry run", pflag.ContinueOnError)
+	flagSet.ParseErrorsWhitelist.UnknownFlags = true
+	flagSet.Bool(flagDryRun, false, "")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return false
+	}
+	dryRun, _ := flagSet.GetBool(flagDryRun)
+
+	return dryRun
+}
+
+// dryRunReport describes what a move, copy or attach would do without making
+// any changes.
+type dryRunReport struct {
+	operation          string
+	targetChannel      *model.Channel
+	postCount          int
+	fileCount          int
+	fileBytes          int64
+	reactionCount      int
+	nonMemberUsernames []string
+	blockers           []string
+}
+
+// buildDryRunReport gathers everything that a move, copy or attach of the post
+// list would change. The participants option is checked along with the
+// blockers; an empty option never blocks the operation.
+func (p *Plugin) buildDryRunReport(operation string, wpl *WranglerPostList, targetChannel *model.Channel, blockers []moveOrCopyBlocker, participants, userID string) (*dryRunReport, error) {
+	report := &dryRunReport{
+		operation:     operation,
+		targetChannel: targetChannel,
+		postCount:     wpl.NumPosts(),
+	}
+	for _, blocker := range blockers {
+		report.blockers = append(report.blockers, blocker.message)
+	}
+
+	var reactions []*model.Reaction
+	for _, post := range wpl.Posts {
+		for _, fileID := range post.FileIds {
+			fileInfo, appErr := p.API.GetFileInfo(fileID)
+			if appErr != nil {
+				return nil, errors.Wrap(appErr, "unable to lookup file info")
+			}
+			report.fileCount++
+			report.fileBytes += fileInfo.Size
+		}
+		reactions = append(reactions, p.getReactionsToCopy(post.Id)...)
+	}
+	report.reactionCount = len(reactions)
+
+	nonMemberIDs, err := p.getNonMemberUserIDs(targetChannel.Id, getParticipantIDs(wpl, reactions))
+	if err != nil {
+		return nil, err
+	}
+	report.nonMemberUsernames = p.getUsernames(nonMemberIDs)
+
+	participantsBlocker := p.getParticipantsBlocker(participants, nonMemberIDs, targetChannel, userID)
+	if len(participantsBlocker) != 0 {
+		report.blockers = append(report.blockers, participantsBlocker)
+	}
+
+	return report, nil
+}
+
+func (r *dryRunReport) String() string {
+	msg := fmt.Sprintf("Dry run: this is what the %s would do. No changes were made.\n", r.operation)
+	msg += fmt.Sprintf(
+		"\n| Target Channel | Messages | Files | File Size | Reactions |\n| -- | -- | -- | -- | -- |\n| %s | %d | %d | %s | %d |\n\n",
+		r.targetChannel.DisplayName, r.postCount, r.fileCount, formatBytes(r.fileBytes), r.reactionCount,
+	)
+
+	if len(r.nonMemberUsernames) != 0 {
+		msg += fmt.Sprintf("Participants who are not members of the target channel: @%s\n", strings.Join(r.nonMemberUsernames, ", @"))
+	} else {
+		msg += "All participants are members of the target channel\n"
+	}
+
+	if len(r.blockers) != 0 {
+		msg += "\nThe operation would be blocked:\n"
+		for _, blocker := range r.blockers {
+			msg += fmt.Sprintf(" - %s\n", blocker)
+		}
+	} else {
+		msg += "\nNo rules would block the operation\n"
+	}
+
+	return msg
+}
+
+// runDryRun returns an ephemeral dry run report for the post list.
+func (p *Plugin) runDryRun(operation string, wpl *WranglerPostList, targetChannel *model.Channel, blockers []moveOrCopyBlocker, participants, userID string) (*model.CommandResponse, bool, error) {
+	if wpl.NumPosts() == 0 {
+		return nil, false, errors.New("The wrangler post list contains no posts")
+	}
+
+	report, err := p.buildDryRunReport(operation, wpl, targetChannel, blockers, participants, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, report.String()), false, nil
+}
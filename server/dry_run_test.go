@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDryRunReport(t *testing.T) {
+	targetChannel := &model.Channel{
+		Id:          model.NewId(),
+		DisplayName: "Target Channel",
+	}
+	member := &model.User{Id: model.NewId(), Username: "member"}
+	poster := &model.User{Id: model.NewId(), Username: "poster"}
+	reactor := &model.User{Id: model.NewId(), Username: "reactor"}
+
+	posts := []*model.Post{
+		{Id: model.NewId(), UserId: member.Id, FileIds: []string{"file1", "file2"}},
+		{Id: model.NewId(), UserId: poster.Id},
+	}
+	wpl := newWranglerPostListFromPosts(posts)
+
+	api := &plugintest.API{}
+	api.On("GetFileInfo", "file1").Return(&model.FileInfo{Size: 1024}, nil)
+	api.On("GetFileInfo", "file2").Return(&model.FileInfo{Size: 512}, nil)
+	api.On("GetReactions", posts[0].Id).Return([]*model.Reaction{{UserId: reactor.Id}, {UserId: poster.Id}}, nil)
+	api.On("GetReactions", posts[1].Id).Return([]*model.Reaction{{UserId: member.Id}}, nil)
+	api.On("GetChannelMember", targetChannel.Id, member.Id).Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", targetChannel.Id, mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetChannelMember", "not.found", nil, "", 404))
+	api.On("GetUser", poster.Id).Return(poster, nil)
+	api.On("GetUser", reactor.Id).Return(reactor, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	blockers := []moveOrCopyBlocker{{message: "blocked by a rule"}}
+	report, err := plugin.buildDryRunReport(operationMove, wpl, targetChannel, blockers, participantsNotify, model.NewId())
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.postCount)
+	assert.Equal(t, 2, report.fileCount)
+	assert.Equal(t, int64(1536), report.fileBytes)
+	assert.Equal(t, 3, report.reactionCount)
+	assert.Equal(t, []string{"poster", "reactor"}, report.nonMemberUsernames)
+	assert.Equal(t, []string{"blocked by a rule"}, report.blockers)
+
+	msg := report.String()
+	assert.Contains(t, msg, "| Target Channel | 2 | 2 | 1.5 KB | 3 |")
+	assert.Contains(t, msg, "@poster, @reactor")
+	assert.Contains(t, msg, " - blocked by a rule")
+
+	t.Run("participants option blocks the operation", func(t *testing.T) {
+		report, err := plugin.buildDryRunReport(operationMove, wpl, targetChannel, nil, participantsBlock, model.NewId())
+		require.NoError(t, err)
+		require.Len(t, report.blockers, 1)
+		assert.Contains(t, report.blockers[0], "these participants are not members of the target channel: @poster, @reactor")
+	})
+}
+
+// countMockCalls returns the number of times a mocked method was called.
+func countMockCalls(m *mock.Mock, methodName string) int {
+	var count int
+	for _, call := range m.Calls {
+		if call.Method == methodName {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestIsDryRun(t *testing.T) {
+	assert.False(t, isDryRun([]string{}))
+	assert.False(t, isDryRun([]string{model.NewId(), model.NewId(), "--silent"}))
+	assert.False(t, isDryRun([]string{model.NewId(), "--dry-run=false"}))
+	assert.True(t, isDryRun([]string{model.NewId(), model.NewId(), "--dry-run"}))
+	assert.True(t, isDryRun([]string{"--participants", "add", "--dry-run", model.NewId(), model.NewId()}))
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fileTransferWorkers is how many files are downloaded and re-uploaded at
+	// the same time. It also bounds how many files are held in memory.
+	fileTransferWorkers = 4
+	// fileTransferAttempts is how many times each step of a file transfer is
+	// tried when it fails with a transient error.
+	fileTransferAttempts = 3
+	// fileTransferProgressInterval is how many files are transferred between
+	// progress log messages.
+	fileTransferProgressInterval = 10
+)
+
+// fileTransfer re-uploads the files attached to posts to a channel. Files are
+// transferred concurrently by a bounded pool of workers.
+type fileTransfer struct {
+	plugin     *Plugin
+	channelID  string
+	workers    int
+	attempts   int
+	retryDelay time.Duration
+}
+
+type fileTransferTask struct {
+	postIndex int
+	fileIndex int
+	fileID    string
+}
+
+type fileTransferResult struct {
+	task      fileTransferTask
+	newFileID string
+	err       error
+}
+
+func (p *Plugin) newFileTransfer(channelID string) *fileTransfer {
+	return &fileTransfer{
+		plugin:     p,
+		channelID:  channelID,
+		workers:    fileTransferWorkers,
+		attempts:   fileTransferAttempts,
+		retryDelay: time.Second,
+	}
+}
+
+// run re-uploads the files attached to the posts and replaces the FileIds of
+// each post with the IDs of the new files. The posts are only changed once
+// every file was transferred.
+func (t *fileTransfer) run(posts []*model.Post) error {
+	var tasks []fileTransferTask
+	newFileIDs := make([][]string, len(posts))
+	for i, post := range posts {
+		newFileIDs[i] = make([]string, len(post.FileIds))
+		for j, fileID := range post.FileIds {
+			tasks = append(tasks, fileTransferTask{postIndex: i, fileIndex: j, fileID: fileID})
+		}
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	workers := t.workers
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+
+	t.plugin.API.LogInfo("Wrangler is re-uploading file attachments",
+		"channel_id", t.channelID,
+		"file_count", len(tasks),
+		"workers", workers,
+	)
+
+	taskQueue := make(chan fileTransferTask)
+	results := make(chan fileTransferResult)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(taskQueue)
+		for _, task := range tasks {
+			select {
+			case taskQueue <- task:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskQueue {
+				newFileID, err := t.transferFile(task.fileID)
+				results <- fileTransferResult{task: task, newFileID: newFileID, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results are collected until every worker has finished, even after a
+	// failure, so that no worker is left blocked on sending its result.
+	var err error
+	fail := func(resultErr error) {
+		if err == nil {
+			err = resultErr
+			close(stop)
+		}
+	}
+	var completed int
+	for result := range results {
+		if result.err != nil {
+			fail(result.err)
+			continue
+		}
+
+		newFileIDs[result.task.postIndex][result.task.fileIndex] = result.newFileID
+
+		completed++
+		if completed%fileTransferProgressInterval == 0 || completed == len(tasks) {
+			t.plugin.API.LogInfo("Wrangler re-uploaded file attachments",
+				"channel_id", t.channelID,
+				"completed", completed,
+				"file_count", len(tasks),
+			)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, post := range posts {
+		if len(post.FileIds) != 0 {
+			post.FileIds = newFileIDs[i]
+		}
+	}
+
+	return nil
+}
+
+// transferFile downloads a file and uploads it to the target channel.
+func (t *fileTransfer) transferFile(fileID string) (string, error) {
+	var oldFileInfo *model.FileInfo
+	appErr := t.withRetries(func() (appErr *model.AppError) {
+		oldFileInfo, appErr = t.plugin.API.GetFileInfo(fileID)
+		return appErr
+	})
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "unable to lookup file info to re-upload")
+	}
+
+	var fileBytes []byte
+	appErr = t.withRetries(func() (appErr *model.AppError) {
+		fileBytes, appErr = t.plugin.API.GetFile(fileID)
+		return appErr
+	})
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "unable to get file bytes to re-upload")
+	}
+
+	var newFileInfo *model.FileInfo
+	appErr = t.withRetries(func() (appErr *model.AppError) {
+		newFileInfo, appErr = t.plugin.API.UploadFile(fileBytes, t.channelID, oldFileInfo.Name)
+		return appErr
+	})
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "unable to re-upload file")
+	}
+
+	return newFileInfo.Id, nil
+}
+
+// withRetries calls f until it succeeds, fails with an error that isn't
+// transient, or runs out of attempts. The delay between attempts grows with
+// each attempt.
+func (t *fileTransfer) withRetries(f func() *model.AppError) *model.AppError {
+	var appErr *model.AppError
+	for attempt := 1; attempt <= t.attempts; attempt++ {
+		appErr = f()
+		if appErr == nil || !isTransientAppError(appErr) {
+			return appErr
+		}
+		if attempt < t.attempts {
+			time.Sleep(time.Duration(attempt) * t.retryDelay)
+		}
+	}
+
+	return appErr
+}
+
+// isTransientAppError returns if an API call that failed with the error might
+// succeed when it is tried again.
+func isTransientAppError(appErr *model.AppError) bool {
+	return appErr.StatusCode >= http.StatusInternalServerError || appErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTransfer(t *testing.T) {
+	channelID := model.NewId()
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("LogInfo", mock.AnythingOfTypeArgument("string"), mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		for i := 0; i < 12; i++ {
+			fileID := fmt.Sprintf("file%d", i)
+			api.On("GetFileInfo", fileID).Return(&model.FileInfo{Id: fileID, Name: fileID + ".png"}, nil)
+			api.On("GetFile", fileID).Return([]byte(fileID), nil)
+			api.On("UploadFile", []byte(fileID), channelID, fileID+".png").Return(&model.FileInfo{Id: "new" + fileID}, nil)
+		}
+
+		return api
+	}
+
+	newTransfer := func(api *plugintest.API) *fileTransfer {
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		transfer := plugin.newFileTransfer(channelID)
+		transfer.retryDelay = 0
+
+		return transfer
+	}
+
+	t.Run("files of every post are transferred in order", func(t *testing.T) {
+		api := setupAPI()
+		transfer := newTransfer(api)
+
+		posts := []*model.Post{
+			{Id: model.NewId(), FileIds: []string{"file0", "file1", "file2", "file3", "file4"}},
+			{Id: model.NewId()},
+			{Id: model.NewId(), FileIds: []string{"file5", "file6", "file7", "file8", "file9", "file10", "file11"}},
+		}
+
+		err := transfer.run(posts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"newfile0", "newfile1", "newfile2", "newfile3", "newfile4"}, []string(posts[0].FileIds))
+		assert.Empty(t, posts[1].FileIds)
+		assert.Equal(t, []string{"newfile5", "newfile6", "newfile7", "newfile8", "newfile9", "newfile10", "newfile11"}, []string(posts[2].FileIds))
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		api := &plugintest.API{}
+		transfer := newTransfer(api)
+
+		err := transfer.run([]*model.Post{{Id: model.NewId()}})
+		require.NoError(t, err)
+	})
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		api := setupAPI()
+		api.On("GetFile", "flaky").Return(nil, model.NewAppError("GetFile", "unavailable", nil, "", 503)).Once()
+		api.On("GetFile", "flaky").Return([]byte("flaky"), nil)
+		api.On("GetFileInfo", "flaky").Return(&model.FileInfo{Id: "flaky", Name: "flaky.png"}, nil)
+		api.On("UploadFile", []byte("flaky"), channelID, "flaky.png").Return(&model.FileInfo{Id: "newflaky"}, nil)
+		transfer := newTransfer(api)
+
+		post := &model.Post{Id: model.NewId(), FileIds: []string{"flaky"}}
+		err := transfer.run([]*model.Post{post})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"newflaky"}, []string(post.FileIds))
+		api.AssertNumberOfCalls(t, "GetFile", 2)
+	})
+
+	t.Run("other errors fail the transfer", func(t *testing.T) {
+		api := setupAPI()
+		api.On("GetFileInfo", "missing").Return(nil, model.NewAppError("GetFileInfo", "not.found", nil, "", 404))
+		transfer := newTransfer(api)
+
+		post := &model.Post{Id: model.NewId(), FileIds: []string{"file0", "missing", "file1"}}
+		err := transfer.run([]*model.Post{post})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to lookup file info to re-upload")
+		assert.Equal(t, []string{"file0", "missing", "file1"}, []string(post.FileIds))
+		api.AssertNotCalled(t, "UploadFile", []byte("missing"), channelID, "missing.png")
+	})
+
+	t.Run("retries run out", func(t *testing.T) {
+		api := setupAPI()
+		api.On("GetFileInfo", "down").Return(nil, model.NewAppError("GetFileInfo", "unavailable", nil, "", 500))
+		transfer := newTransfer(api)
+
+		err := transfer.run([]*model.Post{{Id: model.NewId(), FileIds: []string{"down"}}})
+		require.Error(t, err)
+		api.AssertNumberOfCalls(t, "GetFileInfo", fileTransferAttempts)
+	})
+}
@@ -0,0 +1,634 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	jobKeyPrefix = "job_"
+
+	// jobIndexKey stores the IDs of every stored job, so that jobs can be
+	// listed without listing every KV key. Jobs that expired are removed from
+	// it the next time the jobs are listed.
+	jobIndexKey = "jobs_index"
+
+	// pendingJobIndexKey stores the IDs of jobs that are not done yet, so that
+	// workers can find jobs to claim without listing every KV key.
+	pendingJobIndexKey = "jobs_pending"
+
+	// jobPollInterval is how often the worker checks the queue for jobs that
+	// were queued on other servers in the cluster.
+	jobPollInterval = 15 * time.Second
+
+	// jobLeaseDuration is how long a running job belongs to the server that
+	// claimed it. The lease is renewed while the job runs, so a job with an
+	// expired lease was interrupted and can be claimed again. The lease
+	// outlasts journalStaleAfter so that the interrupted operation's journal
+	// can be resolved before the job is retried.
+	jobLeaseDuration      = journalStaleAfter + jobLeaseRenewInterval
+	jobLeaseRenewInterval = time.Minute
+
+	// jobMaxAttempts is how many times a job is started before it is marked
+	// as failed.
+	jobMaxAttempts = 3
+	// jobUpdateRetries is how many times a job update is retried when the job
+	// is changed by another server at the same time.
+	jobUpdateRetries = 5
+	// jobRetention is how long jobs are kept once they are done.
+	jobRetention = 7 * 24 * time.Hour
+
+	jobStatusPending  = "pending"
+	jobStatusRunning  = "running"
+	jobStatusFinished = "finished"
+	jobStatusFailed   = "failed"
+	jobStatusCanceled = "canceled"
+
+	jobCommandMoveThread   = "move thread"
+	jobCommandCopyThread   = "copy thread"
+	jobCommandMoveMessages = "move messages"
+	jobCommandCopyMessages = "copy messages"
+)
+
+// WranglerJob is a wrangle command that was queued to run in the background
+// because it was too large to finish before the slash command timed out. Jobs
+// are persisted in the plugin KV store so that they survive plugin restarts.
+type WranglerJob struct {
+	ID          string             `json:"id"`
+	UserID      string             `json:"user_id"`
+	Command     string             `json:"command"`
+	Args        []string           `json:"args"`
+	CommandArgs *model.CommandArgs `json:"command_args"`
+	Status      string             `json:"status"`
+	Attempts    int                `json:"attempts"`
+	CreateAt    int64              `json:"create_at"`
+	UpdateAt    int64              `json:"update_at"`
+	FinishAt    int64              `json:"finish_at"`
+
+	// LeaseExpireAt is when a running job is considered abandoned by the
+	// server that claimed it.
+	LeaseExpireAt int64 `json:"lease_expire_at"`
+
+	// Result is the command response of a finished job and Error is the
+	// reason a job failed.
+	Result string `json:"result"`
+	Error  string `json:"error"`
+}
+
+func jobKey(id string) string {
+	return jobKeyPrefix + id
+}
+
+// isDone returns if the job will not run again.
+func (j *WranglerJob) isDone() bool {
+	return j.Status == jobStatusFinished || j.Status == jobStatusFailed || j.Status == jobStatusCanceled
+}
+
+// isClaimable returns if the job is waiting for a server to run it.
+func (j *WranglerJob) isClaimable(now int64) bool {
+	return j.Status == jobStatusPending || (j.Status == jobStatusRunning && j.LeaseExpireAt < now)
+}
+
+// jobHandler runs a queued command as part of the given job.
+type jobHandler func(args []string, extra *model.CommandArgs, job *WranglerJob) (*model.CommandResponse, bool, error)
+
+// getJobHandler returns the command handler that runs a queued command. The
+// job is passed to the handler directly instead of through the command args,
+// so users can't make a command skip the queue.
+func (p *Plugin) getJobHandler(command string) jobHandler {
+	switch command {
+	case jobCommandMoveThread:
+		return p.runMoveThread
+	case jobCommandCopyThread:
+		return p.runCopyThread
+	case jobCommandMoveMessages:
+		return func(args []string, extra *model.CommandArgs, job *WranglerJob) (*model.CommandResponse, bool, error) {
+			return p.runMoveOrCopyMessagesCommand(operationMove, args, extra, job)
+		}
+	case jobCommandCopyMessages:
+		return func(args []string, extra *model.CommandArgs, job *WranglerJob) (*model.CommandResponse, bool, error) {
+			return p.runMoveOrCopyMessagesCommand(operationCopy, args, extra, job)
+		}
+	}
+
+	return nil
+}
+
+// queueLargeJob queues a command as a background job when it wrangles more
+// messages than the configured threshold. A response is returned when the
+// command was queued. Commands that are already running as a job are never
+// queued again.
+func (p *Plugin) queueLargeJob(command string, job *WranglerJob, postCount int, args []string, extra *model.CommandArgs) (*model.CommandResponse, error) {
+	threshold := p.getConfiguration().BackgroundJobThresholdInt()
+	if job != nil || threshold == 0 || postCount <= threshold {
+		return nil, nil
+	}
+
+	job, err := p.enqueueJob(command, args, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf(
+		"This %s includes %d messages, so it has been queued as background job %s. You will be sent a DM when it is done.\nRun `/wrangler jobs show %s` to check on it.",
+		command, postCount, inlineCode(job.ID), job.ID,
+	)), nil
+}
+
+// enqueueJob stores a new pending job and wakes up the worker.
+func (p *Plugin) enqueueJob(command string, args []string, extra *model.CommandArgs) (*WranglerJob, error) {
+	job := &WranglerJob{
+		ID:          model.NewId(),
+		UserID:      extra.UserId,
+		Command:     command,
+		Args:        args,
+		CommandArgs: extra,
+		Status:      jobStatusPending,
+		CreateAt:    model.GetMillis(),
+	}
+
+	saved, err := p.saveJob(job, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, errors.Errorf("job %s already exists", job.ID)
+	}
+
+	_, err = p.updateKVStringList(jobIndexKey, func(ids []string) []string {
+		return append(ids, job.ID)
+	})
+	if err == nil {
+		_, err = p.updateKVStringList(pendingJobIndexKey, func(ids []string) []string {
+			return append(ids, job.ID)
+		})
+	}
+	if err != nil {
+		// A job that isn't in the pending index would never be run. An ID
+		// left behind in the job index is removed once the job is gone.
+		appErr := p.API.KVDelete(jobKey(job.ID))
+		if appErr != nil {
+			p.API.LogError("Unable to remove job that couldn't be queued",
+				"job_id", job.ID,
+				"error", appErr.Error(),
+			)
+		}
+		return nil, err
+	}
+
+	p.API.LogInfo("Wrangler queued a background job",
+		"job_id", job.ID,
+		"user_id", job.UserID,
+		"command", job.Command,
+	)
+
+	p.wakeJobWorker()
+
+	return job, nil
+}
+
+// saveJob stores the job if the stored value still matches oldValue. A nil
+// oldValue only stores the job if it doesn't exist yet. Jobs that are done
+// expire after jobRetention.
+func (p *Plugin) saveJob(job *WranglerJob, oldValue []byte) (bool, error) {
+	job.UpdateAt = model.GetMillis()
+
+	b, err := json.Marshal(job)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to marshal job")
+	}
+
+	options := model.PluginKVSetOptions{
+		Atomic:   true,
+		OldValue: oldValue,
+	}
+	if job.isDone() {
+		options.ExpireInSeconds = int64(jobRetention / time.Second)
+	}
+
+	saved, appErr := p.API.KVSetWithOptions(jobKey(job.ID), b, options)
+	if appErr != nil {
+		return false, errors.Wrap(appErr, "unable to save job")
+	}
+
+	return saved, nil
+}
+
+// getJobWithValue returns the job along with its stored value for use with
+// saveJob. A nil job is returned if it doesn't exist.
+func (p *Plugin) getJobWithValue(id string) (*WranglerJob, []byte, error) {
+	if !model.IsValidId(id) {
+		return nil, nil, nil
+	}
+
+	b, appErr := p.API.KVGet(jobKey(id))
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "unable to get job")
+	}
+	if b == nil {
+		return nil, nil, nil
+	}
+
+	var job WranglerJob
+	err := json.Unmarshal(b, &job)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to unmarshal job")
+	}
+
+	return &job, b, nil
+}
+
+func (p *Plugin) getJob(id string) (*WranglerJob, error) {
+	job, _, err := p.getJobWithValue(id)
+
+	return job, err
+}
+
+// listJobs returns every stored job, oldest first. Jobs that expired are
+// removed from the job index.
+func (p *Plugin) listJobs() ([]*WranglerJob, error) {
+	ids, _, err := p.getKVStringList(jobIndexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*WranglerJob
+	expired := make(map[string]bool)
+	for _, id := range ids {
+		job, err := p.getJob(id)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			expired[id] = true
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	if len(expired) > 0 {
+		_, err = p.updateKVStringList(jobIndexKey, func(ids []string) []string {
+			var remaining []string
+			for _, id := range ids {
+				if !expired[id] {
+					remaining = append(remaining, id)
+				}
+			}
+			return remaining
+		})
+		if err != nil {
+			// The expired jobs are removed again the next time the jobs are
+			// listed.
+			p.API.LogError("Unable to remove expired jobs from the job index",
+				"error", err.Error(),
+			)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreateAt < jobs[j].CreateAt
+	})
+
+	return jobs, nil
+}
+
+// getPendingJobIDs returns the IDs of jobs that are not done yet, in the order
+// they were queued.
+func (p *Plugin) getPendingJobIDs() ([]string, error) {
+	ids, _, err := p.getKVStringList(pendingJobIndexKey)
+
+	return ids, err
+}
+
+// removePendingJobID removes a job that is done from the pending job index.
+// Failures are only logged, as the job is removed again the next time a
+// worker comes across it.
+func (p *Plugin) removePendingJobID(id string) {
+	_, err := p.updateKVStringList(pendingJobIndexKey, func(ids []string) []string {
+		var remaining []string
+		for _, pendingID := range ids {
+			if pendingID != id {
+				remaining = append(remaining, pendingID)
+			}
+		}
+		return remaining
+	})
+	if err != nil {
+		p.API.LogError("Unable to remove job from pending job index",
+			"job_id", id,
+			"error", err.Error(),
+		)
+	}
+}
+
+// updateJob applies update to the stored job. The update is retried with the
+// latest stored job if another server changes the job at the same time, and
+// it returns false to leave the job unchanged. The job is returned along with
+// whether it was updated, or nil if the job doesn't exist.
+func (p *Plugin) updateJob(id string, update func(*WranglerJob) bool) (*WranglerJob, bool, error) {
+	for i := 0; i < jobUpdateRetries; i++ {
+		job, oldValue, err := p.getJobWithValue(id)
+		if err != nil || job == nil {
+			return nil, false, err
+		}
+		if !update(job) {
+			return job, false, nil
+		}
+
+		saved, err := p.saveJob(job, oldValue)
+		if err != nil {
+			return nil, false, err
+		}
+		if saved {
+			return job, true, nil
+		}
+	}
+
+	return nil, false, errors.Errorf("unable to update job %s as it is being changed by another server", id)
+}
+
+// cancelJob cancels a job that hasn't started yet. The job is returned along
+// with whether it was canceled, or nil if it doesn't exist.
+func (p *Plugin) cancelJob(id string) (*WranglerJob, bool, error) {
+	job, canceled, err := p.updateJob(id, func(job *WranglerJob) bool {
+		if job.Status != jobStatusPending {
+			return false
+		}
+		job.Status = jobStatusCanceled
+		job.FinishAt = model.GetMillis()
+
+		return true
+	})
+	if canceled {
+		p.removePendingJobID(id)
+	}
+
+	return job, canceled, err
+}
+
+// startJobWorker starts processing the background job queue. Every server in
+// a cluster runs a worker and jobs are claimed atomically, so each job is only
+// run by one of them at a time.
+func (p *Plugin) startJobWorker() {
+	stop := make(chan struct{})
+	wake := make(chan struct{}, 1)
+	p.jobWorkerStop = stop
+	p.jobWorkerWake = wake
+
+	go func() {
+		ticker := time.NewTicker(jobPollInterval)
+		defer ticker.Stop()
+
+		for {
+			p.runQueuedJobs(stop)
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			case <-wake:
+			}
+		}
+	}()
+}
+
+// stopJobWorker stops the worker from claiming more jobs. A job that is
+// already running is left to finish; if the plugin process exits first, the
+// job's lease expires and it is run again by the next worker to claim it.
+func (p *Plugin) stopJobWorker() {
+	if p.jobWorkerStop != nil {
+		close(p.jobWorkerStop)
+		p.jobWorkerStop = nil
+	}
+}
+
+// wakeJobWorker lets the worker on this server know that a job was queued.
+func (p *Plugin) wakeJobWorker() {
+	if p.jobWorkerWake == nil {
+		return
+	}
+
+	select {
+	case p.jobWorkerWake <- struct{}{}:
+	default:
+	}
+}
+
+// runQueuedJobs runs queued jobs one at a time until there are none left or
+// the worker is stopped.
+func (p *Plugin) runQueuedJobs(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		job, err := p.claimNextJob()
+		if err != nil {
+			p.API.LogError("Unable to claim background job", "error", err.Error())
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		p.runJob(job)
+	}
+}
+
+// claimNextJob claims the oldest job that is waiting to run, including jobs
+// that were interrupted because the server running them stopped. Jobs that
+// have been interrupted too many times are failed instead.
+func (p *Plugin) claimNextJob() (*WranglerJob, error) {
+	ids, err := p.getPendingJobIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	now := model.GetMillis()
+	for _, id := range ids {
+		var interrupted bool
+		job, claimed, err := p.updateJob(id, func(job *WranglerJob) bool {
+			if !job.isClaimable(now) {
+				return false
+			}
+			interrupted = job.Status == jobStatusRunning
+
+			job.Attempts++
+			if job.Attempts > jobMaxAttempts {
+				job.Status = jobStatusFailed
+				job.Error = fmt.Sprintf("the job was interrupted %d times", jobMaxAttempts)
+				job.FinishAt = now
+				job.LeaseExpireAt = 0
+				return true
+			}
+			job.Status = jobStatusRunning
+			job.LeaseExpireAt = now + int64(jobLeaseDuration/time.Millisecond)
+
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if job == nil || job.isDone() {
+			// The job finished or expired without being removed from the
+			// index.
+			if job != nil && claimed {
+				p.notifyJobDone(job)
+			}
+			p.removePendingJobID(id)
+			continue
+		}
+		if !claimed {
+			// The job is running or another server claimed it first.
+			continue
+		}
+
+		if interrupted {
+			// Roll back or finish whatever the interrupted attempt left behind
+			// before running the job again.
+			err = p.resolveUnfinishedJournals()
+			if err != nil {
+				p.API.LogError("Unable to resolve unfinished journals", "error", err.Error())
+			}
+		}
+
+		return job, nil
+	}
+
+	return nil, nil
+}
+
+// runJob runs a claimed job, renewing its lease until the command returns,
+// and lets the user know how it went.
+func (p *Plugin) runJob(job *WranglerJob) {
+	p.API.LogInfo("Wrangler is running a background job",
+		"job_id", job.ID,
+		"user_id", job.UserID,
+		"command", job.Command,
+	)
+
+	done := make(chan struct{})
+	go p.renewJobLease(job.ID, done)
+	response, userError, runErr := p.executeJob(job)
+	close(done)
+
+	finishedJob, _, err := p.updateJob(job.ID, func(job *WranglerJob) bool {
+		job.FinishAt = model.GetMillis()
+		job.LeaseExpireAt = 0
+		if runErr != nil {
+			job.Status = jobStatusFailed
+			job.Error = runErr.Error()
+			return true
+		}
+		if userError {
+			// The command refused to run, for example because the messages
+			// changed after the job was queued.
+			job.Status = jobStatusFailed
+			if response != nil {
+				job.Error = response.Text
+			}
+			return true
+		}
+		job.Status = jobStatusFinished
+		if response != nil {
+			job.Result = response.Text
+		}
+
+		return true
+	})
+	if err != nil {
+		p.API.LogError("Unable to save finished background job",
+			"job_id", job.ID,
+			"error", err.Error(),
+		)
+	}
+	if finishedJob == nil {
+		return
+	}
+	p.removePendingJobID(job.ID)
+
+	if finishedJob.Status == jobStatusFinished && response != nil && response.ResponseType == model.COMMAND_RESPONSE_TYPE_IN_CHANNEL {
+		// Share the summary in the channel the command was run in, just like
+		// the command would have if it hadn't been queued.
+		_, appErr := p.API.CreatePost(&model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: finishedJob.CommandArgs.ChannelId,
+			Message:   response.Text,
+		})
+		if appErr != nil {
+			p.API.LogError("Unable to post background job summary",
+				"job_id", job.ID,
+				"error", appErr.Error(),
+			)
+		}
+	}
+
+	p.notifyJobDone(finishedJob)
+}
+
+// executeJob runs the job's command as part of the job so that the command
+// isn't queued again. The command response is returned along with whether
+// the command refused to run because of a user error.
+func (p *Plugin) executeJob(job *WranglerJob) (*model.CommandResponse, bool, error) {
+	handler := p.getJobHandler(job.Command)
+	if handler == nil {
+		return nil, false, errors.Errorf("unknown job command %s", job.Command)
+	}
+
+	return handler(job.Args, job.CommandArgs, job)
+}
+
+// renewJobLease keeps extending the lease of a running job until done is
+// closed.
+func (p *Plugin) renewJobLease(jobID string, done <-chan struct{}) {
+	ticker := time.NewTicker(jobLeaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		_, _, err := p.updateJob(jobID, func(job *WranglerJob) bool {
+			if job.Status != jobStatusRunning {
+				return false
+			}
+			job.LeaseExpireAt = model.GetMillis() + int64(jobLeaseDuration/time.Millisecond)
+
+			return true
+		})
+		if err != nil {
+			p.API.LogError("Unable to renew background job lease",
+				"job_id", jobID,
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// notifyJobDone sends the user who queued a job a DM with its outcome.
+func (p *Plugin) notifyJobDone(job *WranglerJob) {
+	var msg string
+	if job.Status == jobStatusFailed {
+		msg = fmt.Sprintf("Your background job %s (`/wrangler %s`) failed: %s", inlineCode(job.ID), job.Command, job.Error)
+	} else {
+		msg = fmt.Sprintf("Your background job %s (`/wrangler %s`) has finished.\n\n%s", inlineCode(job.ID), job.Command, job.Result)
+	}
+
+	err := p.PostBotDM(job.UserID, msg)
+	if err != nil {
+		p.API.LogError("Unable to send background job DM to user",
+			"error", err.Error(),
+			"user_id", job.UserID,
+		)
+	}
+}
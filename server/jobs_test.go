@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockKVStore backs the KV store methods of the mock API with an in-memory
+// map, including atomic sets and deletes.
+func mockKVStore(api *plugintest.API) map[string][]byte {
+	var lock sync.Mutex
+	store := make(map[string][]byte)
+
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		lock.Lock()
+		defer lock.Unlock()
+		return store[key]
+	}, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		lock.Lock()
+		defer lock.Unlock()
+		store[key] = value
+		return nil
+	})
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(func(key string, value []byte, options model.PluginKVSetOptions) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		if options.Atomic && !bytes.Equal(store[key], options.OldValue) {
+			return false
+		}
+		store[key] = value
+		return true
+	}, nil)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+		lock.Lock()
+		defer lock.Unlock()
+		delete(store, key)
+		return nil
+	})
+	api.On("KVCompareAndDelete", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, oldValue []byte) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		if !bytes.Equal(store[key], oldValue) {
+			return false
+		}
+		delete(store, key)
+		return true
+	}, nil)
+	api.On("KVList", mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(func(page, perPage int) []string {
+		lock.Lock()
+		defer lock.Unlock()
+		var keys []string
+		for key := range store {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		start := page * perPage
+		if start >= len(keys) {
+			return []string{}
+		}
+		end := start + perPage
+		if end > len(keys) {
+			end = len(keys)
+		}
+		return keys[start:end]
+	}, nil)
+
+	return store
+}
+
+func setupJobsAPI() *plugintest.API {
+	api := &plugintest.API{}
+	mockKVStore(api)
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.Channel{Id: model.NewId()}, nil)
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+	api.On("LogError", mock.AnythingOfTypeArgument("string"), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+
+	return api
+}
+
+func TestQueueLargeJob(t *testing.T) {
+	api := setupJobsAPI()
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{BackgroundJobThreshold: "10"})
+
+	extra := &model.CommandArgs{UserId: model.NewId(), ChannelId: model.NewId()}
+	args := []string{model.NewId(), model.NewId()}
+
+	t.Run("below the threshold", func(t *testing.T) {
+		resp, err := plugin.queueLargeJob(jobCommandMoveThread, nil, 10, args, extra)
+		require.NoError(t, err)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("already running as a job", func(t *testing.T) {
+		resp, err := plugin.queueLargeJob(jobCommandMoveThread, &WranglerJob{ID: model.NewId()}, 11, args, extra)
+		require.NoError(t, err)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("above the threshold", func(t *testing.T) {
+		resp, err := plugin.queueLargeJob(jobCommandMoveThread, nil, 11, args, extra)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		assert.Contains(t, resp.Text, "has been queued as background job")
+
+		jobs, err := plugin.listJobs()
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, jobStatusPending, jobs[0].Status)
+		assert.Equal(t, extra.UserId, jobs[0].UserID)
+		assert.Equal(t, args, jobs[0].Args)
+		assert.Equal(t, extra.ChannelId, jobs[0].CommandArgs.ChannelId)
+	})
+
+	t.Run("no threshold", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{})
+		resp, err := plugin.queueLargeJob(jobCommandMoveThread, nil, 1000, args, extra)
+		require.NoError(t, err)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestClaimNextJob(t *testing.T) {
+	extra := &model.CommandArgs{UserId: model.NewId(), ChannelId: model.NewId()}
+
+	t.Run("claims the oldest pending job once", func(t *testing.T) {
+		var plugin Plugin
+		plugin.SetAPI(setupJobsAPI())
+
+		first, err := plugin.enqueueJob(jobCommandMoveThread, nil, extra)
+		require.NoError(t, err)
+		_, _, err = plugin.updateJob(first.ID, func(job *WranglerJob) bool {
+			job.CreateAt -= 1000
+			return true
+		})
+		require.NoError(t, err)
+		_, err = plugin.enqueueJob(jobCommandCopyThread, nil, extra)
+		require.NoError(t, err)
+
+		job, err := plugin.claimNextJob()
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, first.ID, job.ID)
+		assert.Equal(t, jobStatusRunning, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+
+		second, err := plugin.claimNextJob()
+		require.NoError(t, err)
+		require.NotNil(t, second)
+		assert.NotEqual(t, first.ID, second.ID)
+
+		none, err := plugin.claimNextJob()
+		require.NoError(t, err)
+		assert.Nil(t, none)
+	})
+
+	t.Run("only the pending job index is read", func(t *testing.T) {
+		api := setupJobsAPI()
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		job, err := plugin.enqueueJob(jobCommandMoveThread, nil, extra)
+		require.NoError(t, err)
+
+		claimed, err := plugin.claimNextJob()
+		require.NoError(t, err)
+		require.NotNil(t, claimed)
+		assert.Equal(t, job.ID, claimed.ID)
+		api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reclaims interrupted jobs", func(t *testing.T) {
+		var plugin Plugin
+		plugin.SetAPI(setupJobsAPI())
+
+		job, err := plugin.enqueueJob(jobCommandMoveThread, nil, extra)
+		require.NoError(t, err)
+		_, err = plugin.claimNextJob()
+		require.NoError(t, err)
+
+		_, _, err = plugin.updateJob(job.ID, func(job *WranglerJob) bool {
+			job.LeaseExpireAt = model.GetMillis() - 1
+			return true
+		})
+		require.NoError(t, err)
+
+		job, err = plugin.claimNextJob()
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, 2, job.Attempts)
+	})
+
+	t.Run("fails jobs that were interrupted too many times", func(t *testing.T) {
+		api := setupJobsAPI()
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		job, err := plugin.enqueueJob(jobCommandMoveThread, nil, extra)
+		require.NoError(t, err)
+		_, _, err = plugin.updateJob(job.ID, func(job *WranglerJob) bool {
+			job.Status = jobStatusRunning
+			job.Attempts = jobMaxAttempts
+			job.LeaseExpireAt = model.GetMillis() - 1
+			return true
+		})
+		require.NoError(t, err)
+
+		claimed, err := plugin.claimNextJob()
+		require.NoError(t, err)
+		assert.Nil(t, claimed)
+
+		job, err = plugin.getJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, jobStatusFailed, job.Status)
+		api.AssertCalled(t, "GetDirectChannel", extra.UserId, mock.AnythingOfType("string"))
+	})
+}
+
+func TestRunJob(t *testing.T) {
+	extra := &model.CommandArgs{UserId: model.NewId(), ChannelId: model.NewId()}
+
+	t.Run("command error", func(t *testing.T) {
+		api := setupJobsAPI()
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		_, err := plugin.enqueueJob("unknown command", nil, extra)
+		require.NoError(t, err)
+
+		job, err := plugin.claimNextJob()
+		require.NoError(t, err)
+		require.NotNil(t, job)
+
+		plugin.runJob(job)
+
+		job, err = plugin.getJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, jobStatusFailed, job.Status)
+		assert.Equal(t, "unknown job command unknown command", job.Error)
+		assert.NotZero(t, job.FinishAt)
+		api.AssertCalled(t, "GetDirectChannel", extra.UserId, mock.AnythingOfType("string"))
+
+		pendingIDs, err := plugin.getPendingJobIDs()
+		require.NoError(t, err)
+		assert.Empty(t, pendingIDs)
+	})
+
+	t.Run("command refused to run", func(t *testing.T) {
+		api := setupJobsAPI()
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		_, err := plugin.enqueueJob(jobCommandMoveThread, []string{model.NewId()}, extra)
+		require.NoError(t, err)
+
+		job, err := plugin.claimNextJob()
+		require.NoError(t, err)
+		require.NotNil(t, job)
+
+		plugin.runJob(job)
+
+		job, err = plugin.getJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, jobStatusFailed, job.Status)
+		assert.Equal(t, getMoveThreadMessage(), job.Error)
+		assert.Empty(t, job.Result)
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return strings.Contains(post.Message, "failed:")
+		}))
+	})
+}
+
+func TestListJobs(t *testing.T) {
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	extra := &model.CommandArgs{UserId: model.NewId(), ChannelId: model.NewId()}
+	first, err := plugin.enqueueJob(jobCommandMoveThread, nil, extra)
+	require.NoError(t, err)
+	expired, err := plugin.enqueueJob(jobCommandCopyThread, nil, extra)
+	require.NoError(t, err)
+
+	t.Run("only the job index is read", func(t *testing.T) {
+		jobs, err := plugin.listJobs()
+		require.NoError(t, err)
+		require.Len(t, jobs, 2)
+		api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired jobs are removed from the index", func(t *testing.T) {
+		delete(store, jobKey(expired.ID))
+
+		jobs, err := plugin.listJobs()
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, first.ID, jobs[0].ID)
+
+		ids, _, err := plugin.getKVStringList(jobIndexKey)
+		require.NoError(t, err)
+		assert.Equal(t, []string{first.ID}, ids)
+	})
+}
+
+func TestCancelJob(t *testing.T) {
+	var plugin Plugin
+	plugin.SetAPI(setupJobsAPI())
+
+	extra := &model.CommandArgs{UserId: model.NewId(), ChannelId: model.NewId()}
+	pending, err := plugin.enqueueJob(jobCommandMoveThread, nil, extra)
+	require.NoError(t, err)
+
+	job, canceled, err := plugin.cancelJob(pending.ID)
+	require.NoError(t, err)
+	assert.True(t, canceled)
+	assert.Equal(t, jobStatusCanceled, job.Status)
+
+	pendingIDs, err := plugin.getPendingJobIDs()
+	require.NoError(t, err)
+	assert.Empty(t, pendingIDs)
+
+	job, canceled, err = plugin.cancelJob(pending.ID)
+	require.NoError(t, err)
+	assert.False(t, canceled)
+	assert.Equal(t, jobStatusCanceled, job.Status)
+
+	job, canceled, err = plugin.cancelJob(model.NewId())
+	require.NoError(t, err)
+	assert.False(t, canceled)
+	assert.Nil(t, job)
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	journalKeyPrefix = "journal_"
+
+	// activeJournalIndexKey stores the IDs of journals that haven't been
+	// completed, so that unfinished journals can be found without listing
+	// every KV key.
+	activeJournalIndexKey = "journals_active"
+
+	// kvStringListUpdateRetries is how many times a string list in the KV
+	// store is updated when it is changed by another server at the same time.
+	kvStringListUpdateRetries = 5
+
+	// journalStaleAfter is how long a journal must go without updates before
+	// it is considered abandoned. This prevents one server in a cluster from
+	// rolling back an operation that is still running on another.
+	journalStaleAfter = 5 * time.Minute
+
+	operationMove   = "move"
+	operationCopy   = "copy"
+	operationAttach = "attach"
+	operationMerge  = "merge"
+	operationSplit  = "split"
+	operationDetach = "detach"
+	operationUndo   = "undo"
+)
+
+// WranglerJournal records every post created while a wrangle operation is in
+// progress. It is persisted in the plugin KV store so that partially-completed
+// operations can be rolled back, even if the plugin is restarted part way
+// through.
+type WranglerJournal struct {
+	ID        string `json:"id"`
+	Operation string `json:"operation"`
+	UserID    string `json:"user_id"`
+	CreateAt  int64  `json:"create_at"`
+	UpdateAt  int64  `json:"update_at"`
+
+	// PostIDs are the new posts created by the operation so far.
+	PostIDs []string `json:"post_ids"`
+
+	// Provenance maps each new post whose provenance has been recorded to
+	// the original post it was created from, so that a rollback can remove
+	// the provenance of the posts it deletes.
+	Provenance map[string]string `json:"provenance"`
+
+	// Committed is set once all new posts have been created and the
+	// operation is about to clean up the original posts. A committed journal
+	// is resolved by finishing the cleanup instead of rolling back.
+	Committed      bool     `json:"committed"`
+	CleanupPostIDs []string `json:"cleanup_post_ids"`
+}
+
+func journalKey(id string) string {
+	return journalKeyPrefix + id
+}
+
+// startJournal creates and stores a new journal for an operation.
+func (p *Plugin) startJournal(operation, userID string) (*WranglerJournal, error) {
+	journal := &WranglerJournal{
+		ID:        model.NewId(),
+		Operation: operation,
+		UserID:    userID,
+		CreateAt:  model.GetMillis(),
+	}
+
+	err := p.saveJournal(journal)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.updateKVStringList(activeJournalIndexKey, func(ids []string) []string {
+		return append(ids, journal.ID)
+	})
+	if err != nil {
+		// A journal that isn't in the index would never be resolved, so the
+		// operation can't go ahead without it.
+		appErr := p.API.KVDelete(journalKey(journal.ID))
+		if appErr != nil {
+			p.API.LogError("Unable to remove journal that couldn't be indexed",
+				"journal_id", journal.ID,
+				"error", appErr.Error(),
+			)
+		}
+		return nil, err
+	}
+
+	return journal, nil
+}
+
+func (p *Plugin) saveJournal(journal *WranglerJournal) error {
+	journal.UpdateAt = model.GetMillis()
+
+	b, err := json.Marshal(journal)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal journal")
+	}
+
+	appErr := p.API.KVSet(journalKey(journal.ID), b)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to save journal")
+	}
+
+	return nil
+}
+
+func (p *Plugin) getJournal(id string) (*WranglerJournal, error) {
+	b, appErr := p.API.KVGet(journalKey(id))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get journal")
+	}
+	if b == nil {
+		return nil, nil
+	}
+
+	var journal WranglerJournal
+	err := json.Unmarshal(b, &journal)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal journal")
+	}
+
+	return &journal, nil
+}
+
+// journalPost records a newly-created post in the journal.
+func (p *Plugin) journalPost(journal *WranglerJournal, postID string) error {
+	journal.PostIDs = append(journal.PostIDs, postID)
+
+	return p.saveJournal(journal)
+}
+
+// journalProvenance records in the journal that the provenance of a new post
+// is about to be stored.
+func (p *Plugin) journalProvenance(journal *WranglerJournal, pr *Provenance) error {
+	if journal.Provenance == nil {
+		journal.Provenance = make(map[string]string)
+	}
+	journal.Provenance[pr.PostID] = pr.OriginalPostID
+
+	return p.saveJournal(journal)
+}
+
+// commitJournal marks the journal as committed and records the original posts
+// that will be deleted to finish the operation.
+func (p *Plugin) commitJournal(journal *WranglerJournal, cleanupPostIDs []string) error {
+	journal.Committed = true
+	journal.CleanupPostIDs = cleanupPostIDs
+
+	return p.saveJournal(journal)
+}
+
+// completeJournal removes the journal once an operation has fully completed.
+func (p *Plugin) completeJournal(journal *WranglerJournal) {
+	appErr := p.API.KVDelete(journalKey(journal.ID))
+	if appErr != nil {
+		p.API.LogError("Unable to remove completed journal",
+			"journal_id", journal.ID,
+			"error", appErr.Error(),
+		)
+		return
+	}
+
+	p.removeActiveJournalIDs(map[string]bool{journal.ID: true})
+}
+
+// removeActiveJournalIDs removes journals that are gone from the active
+// journal index. Failures are only logged, as IDs of journals that no longer
+// exist are removed again the next time unfinished journals are resolved.
+func (p *Plugin) removeActiveJournalIDs(remove map[string]bool) {
+	_, err := p.updateKVStringList(activeJournalIndexKey, func(ids []string) []string {
+		var remaining []string
+		for _, id := range ids {
+			if !remove[id] {
+				remaining = append(remaining, id)
+			}
+		}
+		return remaining
+	})
+	if err != nil {
+		p.API.LogError("Unable to remove journals from the active journal index",
+			"error", err.Error(),
+		)
+	}
+}
+
+// rollbackJournal deletes every post created during the journaled operation,
+// along with the provenance recorded for them. Files that were re-uploaded and
+// attached to those posts are deleted along with them by the server. Files
+// that were uploaded but never attached to a post aren't journaled, as the
+// plugin API has no way to delete them. If any post or provenance can't be
+// removed, the journal is kept with what remains so that the rollback is
+// retried on the next plugin activation.
+func (p *Plugin) rollbackJournal(journal *WranglerJournal) error {
+	p.API.LogInfo("Wrangler is rolling back an operation",
+		"journal_id", journal.ID,
+		"operation", journal.Operation,
+		"user_id", journal.UserID,
+	)
+
+	// Delete in reverse order so that replies are removed before their root.
+	var remaining []string
+	notDeleted := make(map[string]bool)
+	for i := len(journal.PostIDs) - 1; i >= 0; i-- {
+		postID := journal.PostIDs[i]
+		appErr := p.API.DeletePost(postID)
+		if appErr != nil && appErr.StatusCode != http.StatusNotFound {
+			remaining = append([]string{postID}, remaining...)
+			notDeleted[postID] = true
+		}
+	}
+
+	// The provenance of posts that weren't deleted is kept until they are.
+	remainingProvenance := make(map[string]string)
+	var provenanceErrors int
+	for postID, originalPostID := range journal.Provenance {
+		if notDeleted[postID] {
+			remainingProvenance[postID] = originalPostID
+			continue
+		}
+
+		err := p.removeProvenance(postID, originalPostID)
+		if err != nil {
+			p.API.LogError("Unable to remove provenance while rolling back",
+				"post_id", postID,
+				"error", err.Error(),
+			)
+			remainingProvenance[postID] = originalPostID
+			provenanceErrors++
+		}
+	}
+
+	if len(remaining) != 0 || provenanceErrors != 0 {
+		journal.PostIDs = remaining
+		journal.Provenance = remainingProvenance
+		err := p.saveJournal(journal)
+		if err != nil {
+			return errors.Wrap(err, "unable to save journal after partial rollback")
+		}
+
+		if len(remaining) != 0 {
+			return errors.Errorf("unable to delete %d posts while rolling back", len(remaining))
+		}
+		return errors.Errorf("unable to remove the provenance of %d posts while rolling back", provenanceErrors)
+	}
+
+	p.completeJournal(journal)
+
+	return nil
+}
+
+// rollbackJournalAndWrap rolls back the journal and returns the original
+// operation error, noting if the rollback failed as well.
+func (p *Plugin) rollbackJournalAndWrap(journal *WranglerJournal, err error) error {
+	rollbackErr := p.rollbackJournal(journal)
+	if rollbackErr != nil {
+		return errors.Wrapf(err, "rollback also failed: %s", rollbackErr.Error())
+	}
+
+	return err
+}
+
+// finishCommittedJournal deletes the original posts recorded in a committed
+// journal and then removes the journal.
+func (p *Plugin) finishCommittedJournal(journal *WranglerJournal) error {
+	for _, postID := range journal.CleanupPostIDs {
+		appErr := p.API.DeletePost(postID)
+		if appErr != nil && appErr.StatusCode != http.StatusNotFound {
+			return errors.Wrapf(appErr, "unable to delete original post %s", postID)
+		}
+	}
+
+	p.completeJournal(journal)
+
+	return nil
+}
+
+// isStale returns if the journal hasn't been updated recently enough for its
+// operation to still be running.
+func (j *WranglerJournal) isStale(now int64) bool {
+	return now-j.UpdateAt > int64(journalStaleAfter/time.Millisecond)
+}
+
+// resolveUnfinishedJournals finds journals left behind by operations that
+// were interrupted by a plugin crash or restart. Uncommitted journals are
+// rolled back and committed journals have their cleanup finished. Journals
+// that were updated recently are checked again once they become stale.
+func (p *Plugin) resolveUnfinishedJournals() error {
+	journalIDs, _, err := p.getKVStringList(activeJournalIndexKey)
+	if err != nil {
+		return err
+	}
+
+	var recheck bool
+	missing := make(map[string]bool)
+	now := model.GetMillis()
+	for _, id := range journalIDs {
+		journal, err := p.getJournal(id)
+		if err != nil {
+			return err
+		}
+		if journal == nil {
+			// The journal was completed without being removed from the
+			// index.
+			missing[id] = true
+			continue
+		}
+		if !journal.isStale(now) {
+			recheck = true
+			continue
+		}
+
+		if journal.Committed {
+			err = p.finishCommittedJournal(journal)
+		} else {
+			err = p.rollbackJournal(journal)
+		}
+		if err != nil {
+			p.API.LogError("Unable to resolve unfinished journal",
+				"journal_id", journal.ID,
+				"error", err.Error(),
+			)
+		}
+	}
+
+	if len(missing) > 0 {
+		p.removeActiveJournalIDs(missing)
+	}
+
+	if recheck {
+		p.scheduleJournalRecheck()
+	}
+
+	return nil
+}
+
+// scheduleJournalRecheck resolves unfinished journals again once the journals
+// that were updated recently have become stale. Only one recheck is scheduled
+// at a time, and none are scheduled once the plugin is deactivated.
+func (p *Plugin) scheduleJournalRecheck() {
+	p.journalRecheckLock.Lock()
+	defer p.journalRecheckLock.Unlock()
+
+	if p.journalRecheckStopped {
+		return
+	}
+	if p.journalRecheckTimer != nil {
+		p.journalRecheckTimer.Stop()
+	}
+
+	p.journalRecheckTimer = time.AfterFunc(journalStaleAfter, func() {
+		err := p.resolveUnfinishedJournals()
+		if err != nil {
+			p.API.LogError("Unable to resolve unfinished journals", "error", err.Error())
+		}
+	})
+}
+
+// stopJournalRecheck stops the scheduled journal recheck.
+func (p *Plugin) stopJournalRecheck() {
+	p.journalRecheckLock.Lock()
+	defer p.journalRecheckLock.Unlock()
+
+	p.journalRecheckStopped = true
+	if p.journalRecheckTimer != nil {
+		p.journalRecheckTimer.Stop()
+		p.journalRecheckTimer = nil
+	}
+}
+
+// getKVStringList returns the list of strings stored under the key along with
+// its stored value for use with an atomic update.
+func (p *Plugin) getKVStringList(key string) ([]string, []byte, error) {
+	b, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, nil, errors.Wrapf(appErr, "unable to get %s", key)
+	}
+	if b == nil {
+		return nil, nil, nil
+	}
+
+	var values []string
+	err := json.Unmarshal(b, &values)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to unmarshal %s", key)
+	}
+
+	return values, b, nil
+}
+
+// updateKVStringList applies update to the list of strings stored under the
+// key. The update is retried with the latest list if another server changes
+// it at the same time. It returns if the list didn't exist before.
+func (p *Plugin) updateKVStringList(key string, update func([]string) []string) (bool, error) {
+	for i := 0; i < kvStringListUpdateRetries; i++ {
+		values, oldValue, err := p.getKVStringList(key)
+		if err != nil {
+			return false, err
+		}
+
+		b, err := json.Marshal(update(values))
+		if err != nil {
+			return false, errors.Wrapf(err, "unable to marshal %s", key)
+		}
+
+		saved, appErr := p.API.KVSetWithOptions(key, b, model.PluginKVSetOptions{
+			Atomic:   true,
+			OldValue: oldValue,
+		})
+		if appErr != nil {
+			return false, errors.Wrapf(appErr, "unable to save %s", key)
+		}
+		if saved {
+			return oldValue == nil, nil
+		}
+	}
+
+	return false, errors.Errorf("unable to update %s as it is being changed by another server", key)
+}
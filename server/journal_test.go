@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackJournal(t *testing.T) {
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		mockKVStore(api)
+		api.On("LogInfo",
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+			mock.AnythingOfTypeArgument("string"),
+		).Return(nil)
+
+		return api
+	}
+
+	t.Run("deletes posts in reverse order", func(t *testing.T) {
+		api := setupAPI()
+		var deleted []string
+		api.On("DeletePost", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+			deleted = append(deleted, args.String(0))
+		})
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		journal, err := plugin.startJournal(operationMove, model.NewId())
+		require.NoError(t, err)
+		require.NoError(t, plugin.journalPost(journal, "post1"))
+		require.NoError(t, plugin.journalPost(journal, "post2"))
+
+		require.NoError(t, plugin.rollbackJournal(journal))
+		assert.Equal(t, []string{"post2", "post1"}, deleted)
+		api.AssertCalled(t, "KVDelete", journalKey(journal.ID))
+
+		ids, _, err := plugin.getKVStringList(activeJournalIndexKey)
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("already deleted posts are ignored", func(t *testing.T) {
+		api := setupAPI()
+		api.On("DeletePost", mock.AnythingOfType("string")).Return(model.NewAppError("DeletePost", "not.found", nil, "", http.StatusNotFound))
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		journal, err := plugin.startJournal(operationCopy, model.NewId())
+		require.NoError(t, err)
+		require.NoError(t, plugin.journalPost(journal, "post1"))
+
+		require.NoError(t, plugin.rollbackJournal(journal))
+		api.AssertCalled(t, "KVDelete", journalKey(journal.ID))
+	})
+
+	t.Run("failed deletes keep the journal", func(t *testing.T) {
+		api := setupAPI()
+		api.On("DeletePost", "post1").Return(nil)
+		api.On("DeletePost", "post2").Return(model.NewAppError("DeletePost", "failed", nil, "", http.StatusInternalServerError))
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		journal, err := plugin.startJournal(operationAttach, model.NewId())
+		require.NoError(t, err)
+		require.NoError(t, plugin.journalPost(journal, "post1"))
+		require.NoError(t, plugin.journalPost(journal, "post2"))
+
+		require.Error(t, plugin.rollbackJournal(journal))
+		assert.Equal(t, []string{"post2"}, journal.PostIDs)
+		api.AssertNotCalled(t, "KVDelete", journalKey(journal.ID))
+	})
+}
+
+func TestResolveUnfinishedJournals(t *testing.T) {
+	stale := model.GetMillis() - 2*int64(journalStaleAfter.Seconds()*1000)
+
+	uncommitted := &WranglerJournal{
+		ID:       model.NewId(),
+		UpdateAt: stale,
+		PostIDs:  []string{"new1"},
+	}
+	committed := &WranglerJournal{
+		ID:             model.NewId(),
+		UpdateAt:       stale,
+		PostIDs:        []string{"new2"},
+		Committed:      true,
+		CleanupPostIDs: []string{"original2"},
+	}
+	running := &WranglerJournal{
+		ID:       model.NewId(),
+		UpdateAt: model.GetMillis(),
+		PostIDs:  []string{"new3"},
+	}
+	completedID := model.NewId()
+
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	for _, journal := range []*WranglerJournal{uncommitted, committed, running} {
+		b, err := json.Marshal(journal)
+		require.NoError(t, err)
+		store[journalKey(journal.ID)] = b
+	}
+	index, err := json.Marshal([]string{uncommitted.ID, committed.ID, running.ID, completedID})
+	require.NoError(t, err)
+	store[activeJournalIndexKey] = index
+
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	require.NoError(t, plugin.resolveUnfinishedJournals())
+	api.AssertCalled(t, "DeletePost", "new1")
+	api.AssertCalled(t, "DeletePost", "original2")
+	api.AssertNotCalled(t, "DeletePost", "new2")
+	api.AssertNotCalled(t, "DeletePost", "new3")
+	api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+	assert.NotContains(t, store, journalKey(uncommitted.ID))
+	assert.NotContains(t, store, journalKey(committed.ID))
+	assert.Contains(t, store, journalKey(running.ID))
+
+	ids, _, err := plugin.getKVStringList(activeJournalIndexKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{running.ID}, ids)
+
+	// The running journal is checked again later, unless the plugin is
+	// deactivated first.
+	require.NotNil(t, plugin.journalRecheckTimer)
+	plugin.stopJournalRecheck()
+	assert.Nil(t, plugin.journalRecheckTimer)
+	plugin.scheduleJournalRecheck()
+	assert.Nil(t, plugin.journalRecheckTimer)
+}
@@ -40,6 +40,38 @@ const manifestStr = `
         "placeholder": "",
         "default": null
       },
+      {
+        "key": "AllowedUsernames",
+        "display_name": "Allowed Usernames",
+        "type": "text",
+        "help_text": "(Optional) A comma separated list of usernames that may use Wrangler. When any of Allowed Email Domain, Allowed Usernames or Allowed Groups is set, users must match at least one of them.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "AllowedGroups",
+        "display_name": "Allowed Groups",
+        "type": "text",
+        "help_text": "(Optional) A comma separated list of group names, including LDAP synced groups, whose members may use Wrangler. Groups without a name can be listed by display name.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "DeniedUsernames",
+        "display_name": "Denied Usernames",
+        "type": "text",
+        "help_text": "(Optional) A comma separated list of usernames that may never use Wrangler, even if they match an allowed email domain, username or group.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "DeniedGroups",
+        "display_name": "Denied Groups",
+        "type": "text",
+        "help_text": "(Optional) A comma separated list of group names, including LDAP synced groups, whose members may never use Wrangler, even if they match an allowed email domain, username or group.",
+        "placeholder": "",
+        "default": null
+      },
       {
         "key": "EnableWebUI",
         "display_name": "Enable Wrangler webapp functionality [BETA]",
@@ -60,7 +92,7 @@ const manifestStr = `
         "key": "MoveThreadMaxCount",
         "display_name": "Max Thread Count Move Size",
         "type": "text",
-        "help_text": "The maximum number of messages in a thread that the plugin is allowed to move. Leave empty for unlimited messages.",
+        "help_text": "The maximum number of messages in a thread, or in all the threads of a message range, that the plugin is allowed to move. Leave empty for unlimited messages.",
         "placeholder": "",
         "default": null
       },
@@ -95,6 +127,206 @@ const manifestStr = `
         "help_text": "Control whether Wrangler is permitted to move message threads from group message channels or not.",
         "placeholder": "",
         "default": false
+      },
+      {
+        "key": "PreserveTimestampsByDefault",
+        "display_name": "Preserve Original Timestamps By Default",
+        "type": "bool",
+        "help_text": "Control whether moved and copied messages keep their original timestamps when the --preserve-timestamps flag is not provided. Messages with preserved timestamps are placed at their original position in the target channel's history.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "MoveOthersPostsRequirePermission",
+        "display_name": "Require Permission To Move Messages Of Others",
+        "type": "bool",
+        "help_text": "When enabled, users can only move, merge, split, attach or detach messages written by other users if they are a channel admin or are allowed to edit or delete the messages of others in the channel. Users can always wrangle their own messages, and anyone can copy messages.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "BackgroundJobThreshold",
+        "display_name": "Background Job Message Threshold",
+        "type": "text",
+        "help_text": "Move and copy operations with more than this many messages are queued as background jobs instead of running while the slash command waits. The user is sent a DM when the job finishes. Leave empty to always run operations right away.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "UndoWindowMinutes",
+        "display_name": "Undo Window (Minutes)",
+        "type": "text",
+        "help_text": "How many minutes after a move, copy or attach the user who ran it can revert it with /wrangler undo. Defaults to 10 minutes when empty.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "FileReuploadMaxCount",
+        "display_name": "Maximum Files Per Command",
+        "type": "text",
+        "help_text": "The most file attachments a single move, copy or attach can re-upload. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "FileReuploadMaxSizeMB",
+        "display_name": "Maximum File Size Per Command (MB)",
+        "type": "text",
+        "help_text": "The most megabytes of file attachments a single move, copy or attach can re-upload. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "MoveAccessLevel",
+        "display_name": "Move Access Level",
+        "type": "dropdown",
+        "help_text": "Who can move messages with the move, merge and split commands. Channel admin access is checked in the channel the messages are moved from and team admin access in its team.",
+        "placeholder": "",
+        "default": "everyone",
+        "options": [
+          {
+            "display_name": "Everyone",
+            "value": "everyone"
+          },
+          {
+            "display_name": "Channel Admins",
+            "value": "channel_admin"
+          },
+          {
+            "display_name": "Team Admins",
+            "value": "team_admin"
+          },
+          {
+            "display_name": "System Admins",
+            "value": "system_admin"
+          }
+        ]
+      },
+      {
+        "key": "CopyAccessLevel",
+        "display_name": "Copy Access Level",
+        "type": "dropdown",
+        "help_text": "Who can copy messages with the copy commands. Channel admin access is checked in the channel the messages are copied from and team admin access in its team.",
+        "placeholder": "",
+        "default": "everyone",
+        "options": [
+          {
+            "display_name": "Everyone",
+            "value": "everyone"
+          },
+          {
+            "display_name": "Channel Admins",
+            "value": "channel_admin"
+          },
+          {
+            "display_name": "Team Admins",
+            "value": "team_admin"
+          },
+          {
+            "display_name": "System Admins",
+            "value": "system_admin"
+          }
+        ]
+      },
+      {
+        "key": "AttachAccessLevel",
+        "display_name": "Attach Access Level",
+        "type": "dropdown",
+        "help_text": "Who can attach messages to threads and detach them again. Channel admin access is checked in the channel of the messages and team admin access in its team.",
+        "placeholder": "",
+        "default": "everyone",
+        "options": [
+          {
+            "display_name": "Everyone",
+            "value": "everyone"
+          },
+          {
+            "display_name": "Channel Admins",
+            "value": "channel_admin"
+          },
+          {
+            "display_name": "Team Admins",
+            "value": "team_admin"
+          },
+          {
+            "display_name": "System Admins",
+            "value": "system_admin"
+          }
+        ]
+      },
+      {
+        "key": "ListAccessLevel",
+        "display_name": "List Access Level",
+        "type": "dropdown",
+        "help_text": "Who can list channels, messages and threads with the list commands. Channel admin access is checked in the channel the command is run in and team admin access in its team.",
+        "placeholder": "",
+        "default": "everyone",
+        "options": [
+          {
+            "display_name": "Everyone",
+            "value": "everyone"
+          },
+          {
+            "display_name": "Channel Admins",
+            "value": "channel_admin"
+          },
+          {
+            "display_name": "Team Admins",
+            "value": "team_admin"
+          },
+          {
+            "display_name": "System Admins",
+            "value": "system_admin"
+          }
+        ]
+      },
+      {
+        "key": "MoveRateLimitPerUser",
+        "display_name": "Move Rate Limit Per User",
+        "type": "text",
+        "help_text": "(Optional) The maximum number of move, merge and split commands each user can run per hour. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "MoveRateLimitGlobal",
+        "display_name": "Move Rate Limit Global",
+        "type": "text",
+        "help_text": "(Optional) The maximum number of move, merge and split commands all users together can run per hour. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "CopyRateLimitPerUser",
+        "display_name": "Copy Rate Limit Per User",
+        "type": "text",
+        "help_text": "(Optional) The maximum number of copy commands each user can run per hour. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "CopyRateLimitGlobal",
+        "display_name": "Copy Rate Limit Global",
+        "type": "text",
+        "help_text": "(Optional) The maximum number of copy commands all users together can run per hour. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "AttachRateLimitPerUser",
+        "display_name": "Attach Rate Limit Per User",
+        "type": "text",
+        "help_text": "(Optional) The maximum number of attach and detach commands each user can run per hour. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "AttachRateLimitGlobal",
+        "display_name": "Attach Rate Limit Global",
+        "type": "text",
+        "help_text": "(Optional) The maximum number of attach and detach commands all users together can run per hour. Leave empty for no limit.",
+        "placeholder": "",
+        "default": null
       }
     ]
   }
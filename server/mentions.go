@@ -0,0 +1,71 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/utils/markdown"
+)
+
+const zeroWidthSpace = "\u200b"
+
+// mentionPattern matches the @ that starts a mention along with the first
+// character of the mentioned name. The server only treats an @ as a mention
+// when it starts a word, so it can't follow a letter, number or another @.
+var mentionPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}@])@([\p{L}\p{N}])`)
+
+// silenceMentions rewrites the @mentions in a post so that creating the post
+// again doesn't notify the mentioned users, @channel, @all or @here a second
+// time. A zero-width space is added after each @, so the message still looks
+// the same to readers but the server no longer finds the mentions. The plugin
+// API has no way to create a post without sending its mention notifications,
+// so the zero-width space is saved in the post's message.
+func silenceMentions(post *model.Post) {
+	post.Message = silenceMessageMentions(post.Message)
+
+	attachments := post.Attachments()
+	if len(attachments) == 0 {
+		return
+	}
+
+	silenced := make([]*model.SlackAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		copied := *attachment
+		copied.Pretext = silenceMessageMentions(copied.Pretext)
+		copied.Text = silenceMessageMentions(copied.Text)
+		silenced = append(silenced, &copied)
+	}
+	post.AddProp("attachments", silenced)
+}
+
+// silenceMessageMentions adds a zero-width space after the @ of every mention
+// in the message. Only text that the server checks for mentions is changed,
+// so code blocks, inline code and link destinations are left untouched.
+func silenceMessageMentions(message string) string {
+	if len(message) == 0 {
+		return message
+	}
+
+	var ranges []markdown.Range
+	markdown.Inspect(message, func(node interface{}) bool {
+		if text, ok := node.(*markdown.Text); ok {
+			ranges = append(ranges, text.Range)
+			return false
+		}
+		return true
+	})
+
+	var silenced string
+	var position int
+	for _, r := range ranges {
+		if r.Position < position || r.End > len(message) {
+			continue
+		}
+		silenced += message[position:r.Position]
+		silenced += mentionPattern.ReplaceAllString(message[r.Position:r.End], "${1}@"+zeroWidthSpace+"${2}")
+		position = r.End
+	}
+	silenced += message[position:]
+
+	return silenced
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSilenceMessageMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"no mentions", "Nothing to see here", "Nothing to see here"},
+		{"user mention", "Thanks @alice!", "Thanks @\u200balice!"},
+		{"channel mentions", "@channel and @here, @all", "@\u200bchannel and @\u200bhere, @\u200ball"},
+		{"mention after punctuation", "(cc @bob.smith)", "(cc @\u200bbob.smith)"},
+		{"email address", "Mail bob@example.com", "Mail bob@example.com"},
+		{"already silenced", "Thanks @\u200balice", "Thanks @\u200balice"},
+		{"inline code", "Run `@alice` as @alice", "Run `@alice` as @\u200balice"},
+		{"code block", "```\n@channel\n```\n@channel", "```\n@channel\n```\n@\u200bchannel"},
+		{"formatted", "**@alice** _and @bob_", "**@\u200balice** _and @\u200bbob_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, silenceMessageMentions(tt.message))
+		})
+	}
+}
+
+func TestSilenceMentions(t *testing.T) {
+	attachment := &model.SlackAttachment{Pretext: "@here", Text: "ping @alice", Title: "@bob"}
+	post := &model.Post{Message: "@channel look"}
+	post.AddProp("attachments", []*model.SlackAttachment{attachment})
+	original := post.Clone()
+
+	silenceMentions(post)
+
+	assert.Equal(t, "@\u200bchannel look", post.Message)
+	attachments := post.Attachments()
+	if assert.Len(t, attachments, 1) {
+		assert.Equal(t, "@\u200bhere", attachments[0].Pretext)
+		assert.Equal(t, "ping @\u200balice", attachments[0].Text)
+		assert.Equal(t, "@bob", attachments[0].Title)
+	}
+
+	assert.Equal(t, "@channel look", original.Message)
+	assert.Equal(t, "ping @alice", original.Attachments()[0].Text)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
@@ -10,25 +11,74 @@ import (
 // validateMoveOrCopy performs validation on a provided post list to determine
 // if all permissions are in place to allow the for the posts to be moved or
 // copied.
-func (p *Plugin) validateMoveOrCopy(wpl *WranglerPostList, originalChannel *model.Channel, targetChannel *model.Channel, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
-	if wpl.NumPosts() == 0 {
-		return nil, false, errors.New("The wrangler post list contains no posts")
+func (p *Plugin) validateMoveOrCopy(operation string, wpl *WranglerPostList, originalChannel *model.Channel, targetChannel *model.Channel, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	return p.validateMoveOrCopyPostLists(operation, []*WranglerPostList{wpl}, originalChannel, targetChannel, extra)
+}
+
+// validateMoveOrCopyPostLists is the same as validateMoveOrCopy, but for
+// several post lists that are wrangled by a single command.
+func (p *Plugin) validateMoveOrCopyPostLists(operation string, wpls []*WranglerPostList, originalChannel *model.Channel, targetChannel *model.Channel, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	for _, wpl := range wpls {
+		if wpl.NumPosts() == 0 {
+			return nil, false, errors.New("The wrangler post list contains no posts")
+		}
+	}
+
+	blockers, err := p.getPostListsBlockers(operation, wpls, originalChannel, targetChannel, extra)
+	if err != nil {
+		return nil, false, err
 	}
+	if len(blockers) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, blockers[0].message), blockers[0].userError, nil
+	}
+
+	return nil, false, nil
+}
+
+// savedMessagesNotMoved is added to the summary of a move. Saved messages are
+// stored as preferences of each user, which the plugin API of the supported
+// server versions can't read or change, so they still point at the deleted
+// original messages.
+const savedMessagesNotMoved = "Saved messages can't be moved to the new messages on this Mattermost server version, so anyone who saved one of the moved messages will need to save it again.\n"
 
+// moveOrCopyBlocker describes a rule that does not allow a post list to be
+// moved or copied.
+type moveOrCopyBlocker struct {
+	message   string
+	userError bool
+}
+
+// getMoveOrCopyBlockers returns every rule that does not allow the post list
+// to be moved or copied to the target channel, in the order they are checked.
+// The operation is the move, copy, merge or split being validated.
+func (p *Plugin) getMoveOrCopyBlockers(operation string, wpl *WranglerPostList, originalChannel *model.Channel, targetChannel *model.Channel, extra *model.CommandArgs) ([]moveOrCopyBlocker, error) {
+	return p.getPostListsBlockers(operation, []*WranglerPostList{wpl}, originalChannel, targetChannel, extra)
+}
+
+// getPostListsBlockers is the same as getMoveOrCopyBlockers, but for several
+// post lists that are wrangled by a single command. The message count and
+// file attachment limits apply to all of the post lists together.
+func (p *Plugin) getPostListsBlockers(operation string, wpls []*WranglerPostList, originalChannel *model.Channel, targetChannel *model.Channel, extra *model.CommandArgs) ([]moveOrCopyBlocker, error) {
+	var blockers []moveOrCopyBlocker
 	config := p.getConfiguration()
 
+	var posts []*model.Post
+	for _, wpl := range wpls {
+		posts = append(posts, wpl.Posts...)
+	}
+
 	switch originalChannel.Type {
 	case model.CHANNEL_PRIVATE:
 		if !config.MoveThreadFromPrivateChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from private channels"), false, nil
+			blockers = append(blockers, moveOrCopyBlocker{message: "Wrangler is currently configured to not allow moving posts from private channels"})
 		}
 	case model.CHANNEL_DIRECT:
 		if !config.MoveThreadFromDirectMessageChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from direct message channels"), false, nil
+			blockers = append(blockers, moveOrCopyBlocker{message: "Wrangler is currently configured to not allow moving posts from direct message channels"})
 		}
 	case model.CHANNEL_GROUP:
 		if !config.MoveThreadFromGroupMessageChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from group message channels"), false, nil
+			blockers = append(blockers, moveOrCopyBlocker{message: "Wrangler is currently configured to not allow moving posts from group message channels"})
 		}
 	}
 
@@ -36,84 +86,267 @@ func (p *Plugin) validateMoveOrCopy(wpl *WranglerPostList, originalChannel *mode
 		// DM and GM channels are "teamless" so it doesn't make sense to check
 		// the MoveThreadToAnotherTeamEnable config when dealing with those.
 		if !config.MoveThreadToAnotherTeamEnable && targetChannel.TeamId != originalChannel.TeamId {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving messages to different teams"), false, nil
+			blockers = append(blockers, moveOrCopyBlocker{message: "Wrangler is currently configured to not allow moving messages to different teams"})
 		}
 	}
 
-	if config.MaxThreadCountMoveSizeInt() != 0 && config.MaxThreadCountMoveSizeInt() < wpl.NumPosts() {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: the thread is %d posts long, but this command is configured to only move threads of up to %d posts", wpl.NumPosts(), config.MaxThreadCountMoveSizeInt())), true, nil
+	if config.MaxThreadCountMoveSizeInt() != 0 && config.MaxThreadCountMoveSizeInt() < len(posts) {
+		message := fmt.Sprintf("Error: the thread is %d posts long, but this command is configured to only move threads of up to %d posts", len(posts), config.MaxThreadCountMoveSizeInt())
+		if len(wpls) > 1 {
+			message = fmt.Sprintf("Error: the %d threads are %d posts long in total, but this command is configured to only move up to %d posts", len(wpls), len(posts), config.MaxThreadCountMoveSizeInt())
+		}
+		blockers = append(blockers, moveOrCopyBlocker{message: message, userError: true})
 	}
 
-	if wpl.RootPost().ChannelId != extra.ChannelId {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: this command must be run from the channel containing the post"), true, nil
+	for _, wpl := range wpls {
+		if wpl.RootPost().ChannelId != extra.ChannelId {
+			blockers = append(blockers, moveOrCopyBlocker{message: "Error: this command must be run from the channel containing the post", userError: true})
+			break
+		}
 	}
 
 	_, appErr := p.API.GetChannelMember(targetChannel.Id, extra.UserId)
 	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", targetChannel.Id)), true, nil
+		blockers = append(blockers, moveOrCopyBlocker{message: fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", targetChannel.Id), userError: true})
+	} else if targetChannel.DeleteAt != 0 {
+		blockers = append(blockers, moveOrCopyBlocker{message: fmt.Sprintf("Error: channel with ID %s has been archived, so messages can't be added to it", targetChannel.Id), userError: true})
+	} else if !p.API.HasPermissionToChannel(extra.UserId, targetChannel.Id, model.PERMISSION_CREATE_POST) {
+		// Read-only channels, such as announcement channels, don't allow
+		// their members to post.
+		blockers = append(blockers, moveOrCopyBlocker{message: fmt.Sprintf("Error: you don't have permission to post in channel with ID %s, so messages can't be added to it", targetChannel.Id), userError: true})
 	}
 
-	if extra.RootId == wpl.RootPost().Id || extra.ParentId == wpl.RootPost().Id {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread"), true, nil
+	for _, wpl := range wpls {
+		if extra.RootId == wpl.RootPost().Id || extra.ParentId == wpl.RootPost().Id {
+			blockers = append(blockers, moveOrCopyBlocker{message: "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread", userError: true})
+			break
+		}
 	}
 
-	return nil, false, nil
-}
+	// Copies leave the original messages in place, so anyone can copy them.
+	if operation != operationCopy {
+		blocker := p.getOthersPostsBlocker(operation, posts, originalChannel.Id, extra.UserId)
+		if blocker != nil {
+			blockers = append(blockers, *blocker)
+		}
+	}
 
-func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *model.Channel) (*model.Post, error) {
-	var appErr *model.AppError
-	var newRootPost *model.Post
+	fileBlockers, err := p.getFileAttachmentBlockers(posts)
+	if err != nil {
+		return nil, err
+	}
+	blockers = append(blockers, fileBlockers...)
 
-	if wpl.ContainsFileAttachments() {
-		// The thread contains at least one attachment. To properly move the
-		// thread, the files will have to be re-uploaded. This is completed
-		// before any messages are moved.
-		// TODO: check number of files that need to be re-uploaded or file size?
-		p.API.LogInfo("Wrangler is re-uploading file attachments",
-			"file_count", wpl.FileAttachmentCount,
-		)
+	return blockers, nil
+}
 
-		for _, post := range wpl.Posts {
-			var newFileIDs []string
-			var fileBytes []byte
-			var oldFileInfo, newFileInfo *model.FileInfo
-			for _, fileID := range post.FileIds {
-				oldFileInfo, appErr = p.API.GetFileInfo(fileID)
-				if appErr != nil {
-					return nil, errors.Wrap(appErr, "unable to lookup file info to re-upload")
-				}
-				fileBytes, appErr = p.API.GetFile(fileID)
-				if appErr != nil {
-					return nil, errors.Wrap(appErr, "unable to get file bytes to re-upload")
-				}
-				newFileInfo, appErr = p.API.UploadFile(fileBytes, targetChannel.Id, oldFileInfo.Name)
-				if appErr != nil {
-					return nil, errors.Wrap(appErr, "unable to re-upload file")
-				}
-
-				newFileIDs = append(newFileIDs, newFileInfo.Id)
+// getFileAttachmentBlockers checks the file attachments of the posts before
+// anything is wrangled, as every file has to be re-uploaded. Blockers are
+// returned if the files exceed the configured limits for a single operation,
+// or if any file is larger than the server allows to be uploaded, so that an
+// operation doesn't fail part of the way through.
+func (p *Plugin) getFileAttachmentBlockers(posts []*model.Post) ([]moveOrCopyBlocker, error) {
+	var fileCount int
+	var fileBytes, largestFileBytes int64
+	var largestFileName string
+	for _, post := range posts {
+		for _, fileID := range post.FileIds {
+			fileInfo, appErr := p.API.GetFileInfo(fileID)
+			if appErr != nil {
+				return nil, errors.Wrap(appErr, "unable to get file info")
 			}
+			fileCount++
+			fileBytes += fileInfo.Size
+			if fileInfo.Size > largestFileBytes {
+				largestFileBytes = fileInfo.Size
+				largestFileName = fileInfo.Name
+			}
+		}
+	}
+	if fileCount == 0 {
+		return nil, nil
+	}
+
+	var blockers []moveOrCopyBlocker
+	config := p.getConfiguration()
 
-			post.FileIds = newFileIDs
+	maxCount := config.FileReuploadMaxCountInt()
+	if maxCount != 0 && fileCount > maxCount {
+		blockers = append(blockers, moveOrCopyBlocker{
+			message:   fmt.Sprintf("Error: the messages have %d file attachments, but Wrangler is configured to only re-upload up to %d files per command", fileCount, maxCount),
+			userError: true,
+		})
+	}
+
+	maxBytes := config.FileReuploadMaxBytes()
+	if maxBytes != 0 && fileBytes > maxBytes {
+		blockers = append(blockers, moveOrCopyBlocker{
+			message:   fmt.Sprintf("Error: the file attachments of the messages are %s in total, but Wrangler is configured to only re-upload up to %s per command", formatBytes(fileBytes), formatBytes(maxBytes)),
+			userError: true,
+		})
+	}
+
+	serverConfig := p.API.GetConfig()
+	if serverConfig != nil && serverConfig.FileSettings.MaxFileSize != nil {
+		serverMaxBytes := *serverConfig.FileSettings.MaxFileSize
+		if largestFileBytes > serverMaxBytes {
+			blockers = append(blockers, moveOrCopyBlocker{
+				message:   fmt.Sprintf("Error: the file %s is %s, which is larger than the maximum file size of %s allowed by the server, so it can't be re-uploaded", largestFileName, formatBytes(largestFileBytes), formatBytes(serverMaxBytes)),
+				userError: true,
+			})
 		}
 	}
 
-	for i, post := range wpl.Posts {
-		var reactions []*model.Reaction
+	return blockers, nil
+}
+
+// getOthersPostsBlocker returns a blocker if the plugin is configured to only
+// let users wrangle the messages of other users when they are allowed to edit
+// or delete them, and the user isn't. Channel admins are always allowed.
+// Messages posted by the Wrangler bot don't belong to other users.
+func (p *Plugin) getOthersPostsBlocker(operation string, posts []*model.Post, channelID, userID string) *moveOrCopyBlocker {
+	if !p.getConfiguration().MoveOthersPostsRequirePermission {
+		return nil
+	}
+
+	var othersPostCount int
+	for _, post := range posts {
+		if post.UserId != userID && post.UserId != p.BotUserID {
+			othersPostCount++
+		}
+	}
+	if othersPostCount == 0 {
+		return nil
+	}
+
+	for _, permission := range []*model.Permission{
+		model.PERMISSION_EDIT_OTHERS_POSTS,
+		model.PERMISSION_DELETE_OTHERS_POSTS,
+		model.PERMISSION_MANAGE_CHANNEL_ROLES,
+	} {
+		if p.API.HasPermissionToChannel(userID, channelID, permission) {
+			return nil
+		}
+	}
+
+	return &moveOrCopyBlocker{
+		message: fmt.Sprintf(
+			"Error: Wrangler is configured to only let you %s messages that you wrote, but %d of the messages were written by other users. Channel admins and users who can edit or delete the messages of others can %s them.",
+			operation, othersPostCount, operation,
+		),
+		userError: true,
+	}
+}
 
-		// Store reactions to be reapplied later.
-		reactions, appErr = p.API.GetReactions(post.Id)
+const (
+	channelPostsPerPage = 200
+	maxChannelPostPages = 50
+)
+
+// getChannelPostsSince returns every post in a channel that was created at or
+// after the given timestamp. Pages of posts are requested newest first until
+// a page reaches back past the timestamp.
+func (p *Plugin) getChannelPostsSince(channelID string, since int64) (*model.PostList, error) {
+	postList := model.NewPostList()
+
+	for page := 0; ; page++ {
+		if page == maxChannelPostPages {
+			return nil, fmt.Errorf("more than %d messages were found since the start of the range", maxChannelPostPages*channelPostsPerPage)
+		}
+
+		pagePostList, appErr := p.API.GetPostsForChannel(channelID, page, channelPostsPerPage)
 		if appErr != nil {
-			// Reaction-based errors are logged, but do not cause the plugin to
-			// abort the move thread process.
-			p.API.LogError("Failed to get reactions on original post", "err", appErr)
+			return nil, errors.Wrap(appErr, "unable to get channel posts")
+		}
+		postList.Extend(pagePostList)
+
+		if len(pagePostList.Order) < channelPostsPerPage {
+			break
+		}
+		oldestPost := pagePostList.Posts[pagePostList.Order[len(pagePostList.Order)-1]]
+		if oldestPost.CreateAt < since {
+			break
+		}
+	}
+
+	return postList, nil
+}
+
+// copyOptions control how copyWranglerPostlist recreates posts.
+type copyOptions struct {
+	// preserveTimestamps keeps the original CreateAt and EditAt values so
+	// that copied posts sort into their historical place and keep their
+	// edited state.
+	preserveTimestamps bool
+
+	// silent rewrites the mentions in the new posts so that the mentioned
+	// users aren't notified again.
+	silent bool
+
+	// operation, userID and originalTeamID are recorded in the provenance of
+	// every new post.
+	operation      string
+	userID         string
+	originalTeamID string
+
+	// targetRootPost is an existing root post in the target channel that
+	// every new post is created as a reply to. When it isn't set, the first
+	// post becomes the root of a new thread.
+	targetRootPost *model.Post
+}
+
+// copyWranglerPostlist creates copies of the posts in the post list in the
+// target channel. Every new post and file is recorded in the provided journal
+// so that it can be removed if the operation fails. The root post of the
+// thread the posts were copied to is returned.
+func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *model.Channel, options copyOptions, journal *WranglerJournal) (*model.Post, error) {
+	var appErr *model.AppError
+	newRootPost := options.targetRootPost
+
+	if wpl.ContainsFileAttachments() {
+		// The thread contains at least one attachment. To properly move the
+		// thread, the files will have to be re-uploaded. This is completed
+		// before any messages are moved. The files have already been checked
+		// against the re-upload limits by getMoveOrCopyBlockers.
+		err := p.newFileTransfer(targetChannel.Id).run(wpl.Posts)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	// Permalinks between the copied posts are pointed at the new posts.
+	rewriter, err := p.newPermalinkRewriter(wpl, targetChannel)
+	if err != nil {
+		return nil, err
+	}
+	var newPosts []*model.Post
+
+	for i, post := range wpl.Posts {
+		reactions := p.getReactionsToCopy(post.Id)
 
 		newPost := post.Clone()
 		cleanPost(newPost)
+		newPost.IsPinned = post.IsPinned
+		if options.preserveTimestamps {
+			newPost.CreateAt = post.CreateAt
+			newPost.EditAt = post.EditAt
+		}
 		newPost.ChannelId = targetChannel.Id
+		if options.silent {
+			silenceMentions(newPost)
+		}
+		if rewriter != nil {
+			newPost.Message = rewriter.rewrite(newPost.Message)
+		}
 
-		if i == 0 {
+		provenance := newProvenance(post, options.originalTeamID, options.operation, options.userID)
+		provenance.addToPost(newPost)
+
+		if i == 0 && newRootPost == nil {
+			// The first post becomes the new root, even if it was a reply in
+			// the original thread.
+			newPost.RootId = ""
+			newPost.ParentId = ""
 			newPost, appErr = p.API.CreatePost(newPost)
 			if appErr != nil {
 				return nil, errors.Wrap(appErr, "unable to create new root post")
@@ -127,17 +360,156 @@ func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *mode
 				return nil, errors.Wrap(appErr, "unable to create new post")
 			}
 		}
+		err = p.journalPost(journal, newPost.Id)
+		if err != nil {
+			return nil, err
+		}
+		if rewriter != nil {
+			rewriter.newPostIDs[post.Id] = newPost.Id
+			newPosts = append(newPosts, newPost)
+		}
 
-		for _, reaction := range reactions {
-			reaction.PostId = newPost.Id
-			_, appErr = p.API.AddReaction(reaction)
-			if appErr != nil {
-				// Reaction-based errors are logged, but do not cause the plugin to
-				// abort the move thread process.
-				p.API.LogError("Failed to reapply reactions to post", "err", appErr)
-			}
+		provenance.PostID = newPost.Id
+		err = p.recordProvenance(journal, provenance)
+		if err != nil {
+			return nil, err
+		}
+
+		p.copyReactions(reactions, newPost.Id)
+	}
+
+	if rewriter != nil {
+		err = p.rewriteLaterPermalinks(rewriter, newPosts)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return newRootPost, nil
 }
+
+// cleanupOriginalPosts commits the journal and then deletes the original posts
+// of a moved post list. Full threads are removed by deleting the root post,
+// which also marks all replies as deleted. Partial threads must have each post
+// deleted individually since the original root post is kept.
+func (p *Plugin) cleanupOriginalPosts(wpl *WranglerPostList, journal *WranglerJournal) error {
+	return p.cleanupOriginalPostLists([]*WranglerPostList{wpl}, journal)
+}
+
+// cleanupOriginalPostLists is the same as cleanupOriginalPosts, but deletes
+// the original posts of several moved post lists under a single journal.
+func (p *Plugin) cleanupOriginalPostLists(wpls []*WranglerPostList, journal *WranglerJournal) error {
+	var cleanupIDs []string
+	for _, wpl := range wpls {
+		if wpl.IsFullThread() {
+			cleanupIDs = append(cleanupIDs, wpl.RootPost().Id)
+			continue
+		}
+		for _, post := range wpl.Posts {
+			cleanupIDs = append(cleanupIDs, post.Id)
+		}
+	}
+
+	err := p.commitJournal(journal, cleanupIDs)
+	if err != nil {
+		return p.rollbackJournalAndWrap(journal, err)
+	}
+
+	for i, postID := range cleanupIDs {
+		appErr := p.API.DeletePost(postID)
+		if appErr != nil {
+			err = errors.Wrap(appErr, "unable to delete post")
+			if i == 0 {
+				return p.rollbackJournalAndWrap(journal, err)
+			}
+
+			// Some of the original posts are already gone, so the operation
+			// can only be finished going forward. The committed journal is
+			// kept so that the remaining cleanup is retried later.
+			return err
+		}
+	}
+	p.completeJournal(journal)
+
+	return nil
+}
+
+// getReactionsToCopy returns the reactions on a post so that they can be
+// reapplied to a copy of it later.
+func (p *Plugin) getReactionsToCopy(postID string) []*model.Reaction {
+	reactions, appErr := p.API.GetReactions(postID)
+	if appErr != nil {
+		// Reaction-based errors are logged, but do not cause the plugin to
+		// abort the move thread process.
+		p.API.LogError("Failed to get reactions on original post", "err", appErr)
+	}
+
+	return reactions
+}
+
+// copyReactions applies the provided reactions to a new post.
+func (p *Plugin) copyReactions(reactions []*model.Reaction, newPostID string) {
+	for _, reaction := range reactions {
+		reaction.PostId = newPostID
+		_, appErr := p.API.AddReaction(reaction)
+		if appErr != nil {
+			// Reaction-based errors are logged, but do not cause the plugin to
+			// abort the move thread process.
+			p.API.LogError("Failed to reapply reactions to post", "err", appErr)
+		}
+	}
+}
+
+// getParticipantIDs returns the IDs of every user who posted in the post list
+// or reacted to one of its posts, without duplicates.
+func getParticipantIDs(wpl *WranglerPostList, reactions []*model.Reaction) []string {
+	userIDs := append([]string{}, wpl.ThreadUserIDs...)
+	seen := make(map[string]bool)
+	for _, userID := range userIDs {
+		seen[userID] = true
+	}
+	for _, reaction := range reactions {
+		if !seen[reaction.UserId] {
+			seen[reaction.UserId] = true
+			userIDs = append(userIDs, reaction.UserId)
+		}
+	}
+
+	return userIDs
+}
+
+// getNonMemberUserIDs returns the IDs of the provided users who are not
+// members of a channel. The Wrangler bot is never included.
+func (p *Plugin) getNonMemberUserIDs(channelID string, userIDs []string) ([]string, error) {
+	var nonMemberIDs []string
+	for _, userID := range userIDs {
+		if userID == p.BotUserID {
+			continue
+		}
+		_, appErr := p.API.GetChannelMember(channelID, userID)
+		if appErr != nil {
+			if appErr.StatusCode != http.StatusNotFound {
+				return nil, errors.Wrap(appErr, "unable to get channel member")
+			}
+			nonMemberIDs = append(nonMemberIDs, userID)
+		}
+	}
+
+	return nonMemberIDs, nil
+}
+
+// getUsernames returns the usernames of the provided users. The user ID is
+// used for any user that can't be found.
+func (p *Plugin) getUsernames(userIDs []string) []string {
+	var usernames []string
+	for _, userID := range userIDs {
+		user, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			usernames = append(usernames, userID)
+			continue
+		}
+		usernames = append(usernames, user.Username)
+	}
+
+	return usernames
+}
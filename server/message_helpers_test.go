@@ -0,0 +1,199 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMoveOrCopyBlockers(t *testing.T) {
+	userID := model.NewId()
+	teamID := model.NewId()
+	originalChannel := &model.Channel{Id: model.NewId(), TeamId: teamID, Type: model.CHANNEL_OPEN}
+	targetChannel := &model.Channel{Id: model.NewId(), TeamId: teamID, Type: model.CHANNEL_OPEN}
+	archivedChannel := &model.Channel{Id: model.NewId(), TeamId: teamID, Type: model.CHANNEL_OPEN, DeleteAt: model.GetMillis()}
+	readOnlyChannel := &model.Channel{Id: model.NewId(), TeamId: teamID, Type: model.CHANNEL_OPEN}
+	otherChannel := &model.Channel{Id: model.NewId(), TeamId: teamID, Type: model.CHANNEL_OPEN}
+
+	wpl := newWranglerPostListFromPosts([]*model.Post{{Id: model.NewId(), UserId: userID, ChannelId: originalChannel.Id}})
+	extra := &model.CommandArgs{UserId: userID, ChannelId: originalChannel.Id}
+
+	api := &plugintest.API{}
+	api.On("GetChannelMember", otherChannel.Id, userID).Return(nil, model.NewAppError("GetChannelMember", "not.found", nil, "", 404))
+	api.On("GetChannelMember", mock.AnythingOfType("string"), userID).Return(mockGenerateChannelMember(), nil)
+	api.On("HasPermissionToChannel", userID, readOnlyChannel.Id, model.PERMISSION_CREATE_POST).Return(false)
+	api.On("HasPermissionToChannel", userID, mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("allowed", func(t *testing.T) {
+		blockers, err := plugin.getMoveOrCopyBlockers(operationMove, wpl, originalChannel, targetChannel, extra)
+		require.NoError(t, err)
+		assert.Empty(t, blockers)
+	})
+
+	t.Run("not a member of the target channel", func(t *testing.T) {
+		blockers, err := plugin.getMoveOrCopyBlockers(operationMove, wpl, originalChannel, otherChannel, extra)
+		require.NoError(t, err)
+		if assert.Len(t, blockers, 1) {
+			assert.Contains(t, blockers[0].message, "doesn't exist or you are not a member")
+			assert.True(t, blockers[0].userError)
+		}
+	})
+
+	t.Run("archived target channel", func(t *testing.T) {
+		blockers, err := plugin.getMoveOrCopyBlockers(operationMove, wpl, originalChannel, archivedChannel, extra)
+		require.NoError(t, err)
+		if assert.Len(t, blockers, 1) {
+			assert.Contains(t, blockers[0].message, "has been archived")
+			assert.True(t, blockers[0].userError)
+		}
+	})
+
+	t.Run("read-only target channel", func(t *testing.T) {
+		blockers, err := plugin.getMoveOrCopyBlockers(operationMove, wpl, originalChannel, readOnlyChannel, extra)
+		require.NoError(t, err)
+		if assert.Len(t, blockers, 1) {
+			assert.Contains(t, blockers[0].message, "you don't have permission to post in channel")
+			assert.True(t, blockers[0].userError)
+		}
+	})
+}
+
+func TestGetOthersPostsBlocker(t *testing.T) {
+	authorID := model.NewId()
+	moderatorID := model.NewId()
+	channelAdminID := model.NewId()
+	botID := model.NewId()
+	channelID := model.NewId()
+
+	posts := []*model.Post{
+		{Id: model.NewId(), UserId: authorID},
+		{Id: model.NewId(), UserId: botID},
+	}
+	othersPosts := append(posts, &model.Post{Id: model.NewId(), UserId: model.NewId()})
+
+	api := &plugintest.API{}
+	api.On("HasPermissionToChannel", moderatorID, channelID, model.PERMISSION_EDIT_OTHERS_POSTS).Return(false)
+	api.On("HasPermissionToChannel", moderatorID, channelID, model.PERMISSION_DELETE_OTHERS_POSTS).Return(true)
+	api.On("HasPermissionToChannel", channelAdminID, channelID, model.PERMISSION_MANAGE_CHANNEL_ROLES).Return(true)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), channelID, mock.Anything).Return(false)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.BotUserID = botID
+
+	t.Run("disabled", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{})
+		assert.Nil(t, plugin.getOthersPostsBlocker(operationMove, othersPosts, channelID, authorID))
+	})
+
+	plugin.setConfiguration(&configuration{MoveOthersPostsRequirePermission: true})
+
+	t.Run("own and bot posts", func(t *testing.T) {
+		assert.Nil(t, plugin.getOthersPostsBlocker(operationMove, posts, channelID, authorID))
+	})
+
+	t.Run("posts of other users", func(t *testing.T) {
+		blocker := plugin.getOthersPostsBlocker(operationAttach, othersPosts, channelID, authorID)
+		if assert.NotNil(t, blocker) {
+			assert.True(t, blocker.userError)
+			assert.Contains(t, blocker.message, "only let you attach messages that you wrote, but 1 of the messages were written by other users")
+		}
+	})
+
+	t.Run("permission to delete the posts of others", func(t *testing.T) {
+		assert.Nil(t, plugin.getOthersPostsBlocker(operationMove, othersPosts, channelID, moderatorID))
+	})
+
+	t.Run("channel admin", func(t *testing.T) {
+		assert.Nil(t, plugin.getOthersPostsBlocker(operationMove, othersPosts, channelID, channelAdminID))
+	})
+
+	t.Run("copies are not restricted", func(t *testing.T) {
+		wpl := newWranglerPostListFromPosts([]*model.Post{{Id: model.NewId(), UserId: model.NewId(), ChannelId: channelID}})
+		extra := &model.CommandArgs{UserId: authorID, ChannelId: channelID}
+		originalChannel := &model.Channel{Id: channelID, Type: model.CHANNEL_OPEN}
+		targetChannel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_OPEN}
+		api.On("GetChannelMember", targetChannel.Id, authorID).Return(mockGenerateChannelMember(), nil)
+		api.On("HasPermissionToChannel", authorID, targetChannel.Id, model.PERMISSION_CREATE_POST).Return(true)
+
+		blockers, err := plugin.getMoveOrCopyBlockers(operationCopy, wpl, originalChannel, targetChannel, extra)
+		require.NoError(t, err)
+		assert.Empty(t, blockers)
+
+		blockers, err = plugin.getMoveOrCopyBlockers(operationMove, wpl, originalChannel, targetChannel, extra)
+		require.NoError(t, err)
+		assert.Len(t, blockers, 1)
+	})
+}
+
+func TestGetFileAttachmentBlockers(t *testing.T) {
+	maxFileSize := int64(10 * 1024 * 1024)
+	config := &model.Config{}
+	config.FileSettings.MaxFileSize = &maxFileSize
+
+	api := &plugintest.API{}
+	api.On("GetConfig").Return(config)
+	api.On("GetFileInfo", "small").Return(&model.FileInfo{Name: "small.png", Size: 1024 * 1024}, nil)
+	api.On("GetFileInfo", "large").Return(&model.FileInfo{Name: "large.zip", Size: 20 * 1024 * 1024}, nil)
+	api.On("GetFileInfo", "missing").Return(nil, model.NewAppError("GetFileInfo", "not.found", nil, "", 404))
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{})
+
+	t.Run("no files", func(t *testing.T) {
+		blockers, err := plugin.getFileAttachmentBlockers([]*model.Post{{Id: model.NewId()}})
+		require.NoError(t, err)
+		assert.Empty(t, blockers)
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		blockers, err := plugin.getFileAttachmentBlockers([]*model.Post{{Id: model.NewId(), FileIds: []string{"small", "small"}}})
+		require.NoError(t, err)
+		assert.Empty(t, blockers)
+	})
+
+	t.Run("file larger than the server allows", func(t *testing.T) {
+		blockers, err := plugin.getFileAttachmentBlockers([]*model.Post{{Id: model.NewId(), FileIds: []string{"small", "large"}}})
+		require.NoError(t, err)
+		if assert.Len(t, blockers, 1) {
+			assert.True(t, blockers[0].userError)
+			assert.Contains(t, blockers[0].message, "the file large.zip is 20.0 MB, which is larger than the maximum file size of 10.0 MB")
+		}
+	})
+
+	t.Run("unable to get file info", func(t *testing.T) {
+		_, err := plugin.getFileAttachmentBlockers([]*model.Post{{Id: model.NewId(), FileIds: []string{"missing"}}})
+		require.Error(t, err)
+	})
+
+	plugin.setConfiguration(&configuration{
+		FileReuploadMaxCount:  "2",
+		FileReuploadMaxSizeMB: "2",
+	})
+
+	t.Run("at the configured limits", func(t *testing.T) {
+		blockers, err := plugin.getFileAttachmentBlockers([]*model.Post{
+			{Id: model.NewId(), FileIds: []string{"small"}},
+			{Id: model.NewId(), FileIds: []string{"small"}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, blockers)
+	})
+
+	t.Run("too many files", func(t *testing.T) {
+		blockers, err := plugin.getFileAttachmentBlockers([]*model.Post{{Id: model.NewId(), FileIds: []string{"small", "small", "small"}}})
+		require.NoError(t, err)
+		if assert.Len(t, blockers, 2) {
+			assert.Contains(t, blockers[0].message, "the messages have 3 file attachments, but Wrangler is configured to only re-upload up to 2 files per command")
+			assert.Contains(t, blockers[1].message, "the file attachments of the messages are 3.0 MB in total, but Wrangler is configured to only re-upload up to 2.0 MB per command")
+		}
+	})
+}
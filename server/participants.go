@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagParticipants = "participants"
+
+	// participantsAdd adds participants who are not members of the target
+	// channel to it after the messages are wrangled.
+	participantsAdd = "add"
+	// participantsNotify sends participants who are not members of the target
+	// channel a DM with a link to the wrangled messages.
+	participantsNotify = "notify"
+	// participantsBlock refuses to wrangle messages when any participant is
+	// not a member of the target channel.
+	participantsBlock = "block"
+)
+
+func addParticipantsFlag(flagSet *pflag.FlagSet) {
+	flagSet.String(flagParticipants, participantsNotify, fmt.Sprintf("How to handle participants who are not members of the target channel: %s, %s or %s", participantsAdd, participantsNotify, participantsBlock))
+}
+
+func getParticipantsFlag(flagSet *pflag.FlagSet) (string, error) {
+	participants, err := flagSet.GetString(flagParticipants)
+	if err != nil {
+		return "", err
+	}
+
+	switch participants {
+	case participantsAdd, participantsNotify, participantsBlock:
+		return participants, nil
+	}
+
+	return "", fmt.Errorf("%s (%s) must be one of %s, %s or %s", flagParticipants, participants, participantsAdd, participantsNotify, participantsBlock)
+}
+
+// getNonMemberParticipantIDs returns the IDs of every user who posted in or
+// reacted to the post lists and is not a member of the target channel.
+func (p *Plugin) getNonMemberParticipantIDs(wpls []*WranglerPostList, targetChannelID string) ([]string, error) {
+	var userIDs []string
+	seen := make(map[string]bool)
+	for _, wpl := range wpls {
+		var reactions []*model.Reaction
+		for _, post := range wpl.Posts {
+			reactions = append(reactions, p.getReactionsToCopy(post.Id)...)
+		}
+		for _, userID := range getParticipantIDs(wpl, reactions) {
+			if !seen[userID] {
+				seen[userID] = true
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+
+	return p.getNonMemberUserIDs(targetChannelID, userIDs)
+}
+
+// prepareNonMemberParticipants checks the participants option before any
+// messages are wrangled. The IDs of participants who are not members of the
+// target channel are returned so that they can be added or notified once the
+// messages have been wrangled. A response is returned when the operation must
+// not go ahead.
+func (p *Plugin) prepareNonMemberParticipants(option string, wpls []*WranglerPostList, targetChannel *model.Channel, userID string) ([]string, *model.CommandResponse, error) {
+	nonMemberIDs, err := p.getNonMemberParticipantIDs(wpls, targetChannel.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blocker := p.getParticipantsBlocker(option, nonMemberIDs, targetChannel, userID)
+	if len(blocker) != 0 {
+		return nil, getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: %s", blocker)), nil
+	}
+
+	return nonMemberIDs, nil, nil
+}
+
+// getParticipantsBlocker returns why the participants option refuses the
+// operation, or an empty string if the operation can go ahead.
+func (p *Plugin) getParticipantsBlocker(option string, nonMemberIDs []string, targetChannel *model.Channel, userID string) string {
+	if len(nonMemberIDs) == 0 {
+		return ""
+	}
+
+	switch option {
+	case participantsBlock:
+		return fmt.Sprintf(
+			"these participants are not members of the target channel: @%s; add them to the channel first or run the command with --%s=%s or --%s=%s",
+			strings.Join(p.getUsernames(nonMemberIDs), ", @"), flagParticipants, participantsAdd, flagParticipants, participantsNotify,
+		)
+	case participantsAdd:
+		if targetChannel.IsGroupOrDirect() {
+			return "participants can't be added to direct or group message channels"
+		}
+		permission := model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS
+		if targetChannel.Type == model.CHANNEL_PRIVATE {
+			permission = model.PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS
+		}
+		if !p.API.HasPermissionToChannel(userID, targetChannel.Id, permission) {
+			return "you don't have permission to add members to the target channel"
+		}
+	}
+
+	return ""
+}
+
+// finishNonMemberParticipants applies the participants option once the
+// messages have been wrangled, so that a failed operation never changes the
+// members of the target channel. Participants who can't be added to the
+// channel are notified instead.
+func (p *Plugin) finishNonMemberParticipants(option string, userIDs []string, targetChannel *model.Channel, newPostLink string) {
+	if option == participantsAdd {
+		var notAddedIDs []string
+		for _, userID := range userIDs {
+			_, appErr := p.API.AddChannelMember(targetChannel.Id, userID)
+			if appErr != nil {
+				p.API.LogError("Unable to add participant to target channel",
+					"error", appErr.Error(),
+					"user_id", userID,
+				)
+				notAddedIDs = append(notAddedIDs, userID)
+			}
+		}
+		userIDs = notAddedIDs
+	}
+
+	p.notifyNonMemberParticipants(userIDs, targetChannel, newPostLink)
+}
+
+// notifyNonMemberParticipants lets participants who are not members of the
+// target channel know where the messages they took part in were wrangled to.
+func (p *Plugin) notifyNonMemberParticipants(userIDs []string, targetChannel *model.Channel, newPostLink string) {
+	for _, userID := range userIDs {
+		err := p.PostBotDM(userID, fmt.Sprintf(
+			"Someone wrangled messages you took part in to ~%s, which you are not a member of: %s\nAsk a member of the channel to add you if you need access.",
+			targetChannel.Name, newPostLink,
+		))
+		if err != nil {
+			p.API.LogError("Unable to send participant DM to user",
+				"error", err.Error(),
+				"user_id", userID,
+			)
+		}
+	}
+}
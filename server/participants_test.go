@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareNonMemberParticipants(t *testing.T) {
+	targetChannel := &model.Channel{
+		Id:   model.NewId(),
+		Name: "target-channel",
+		Type: model.CHANNEL_OPEN,
+	}
+	directChannel := &model.Channel{
+		Id:   model.NewId(),
+		Type: model.CHANNEL_DIRECT,
+	}
+	actingUserID := model.NewId()
+	member := &model.User{Id: model.NewId(), Username: "member"}
+	poster := &model.User{Id: model.NewId(), Username: "poster"}
+	reactor := &model.User{Id: model.NewId(), Username: "reactor"}
+
+	wpl := newWranglerPostListFromPosts([]*model.Post{
+		{Id: model.NewId(), UserId: member.Id},
+		{Id: model.NewId(), UserId: poster.Id},
+	})
+	wpls := []*WranglerPostList{wpl}
+
+	setupAPI := func(canManageMembers bool) *plugintest.API {
+		api := &plugintest.API{}
+		api.On("GetReactions", wpl.Posts[0].Id).Return([]*model.Reaction{{UserId: reactor.Id}}, nil)
+		api.On("GetReactions", wpl.Posts[1].Id).Return([]*model.Reaction{}, nil)
+		api.On("GetChannelMember", mock.AnythingOfType("string"), member.Id).Return(&model.ChannelMember{}, nil)
+		api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, model.NewAppError("GetChannelMember", "not.found", nil, "", 404))
+		api.On("GetUser", poster.Id).Return(poster, nil)
+		api.On("GetUser", reactor.Id).Return(reactor, nil)
+		api.On("HasPermissionToChannel", actingUserID, targetChannel.Id, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS).Return(canManageMembers)
+		api.On("AddChannelMember", targetChannel.Id, mock.AnythingOfType("string")).Return(&model.ChannelMember{}, nil)
+
+		return api
+	}
+
+	t.Run("notify", func(t *testing.T) {
+		var plugin Plugin
+		plugin.SetAPI(setupAPI(false))
+
+		nonMemberIDs, response, err := plugin.prepareNonMemberParticipants(participantsNotify, wpls, targetChannel, actingUserID)
+		require.NoError(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, []string{poster.Id, reactor.Id}, nonMemberIDs)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		var plugin Plugin
+		plugin.SetAPI(setupAPI(false))
+
+		nonMemberIDs, response, err := plugin.prepareNonMemberParticipants(participantsBlock, wpls, targetChannel, actingUserID)
+		require.NoError(t, err)
+		assert.Empty(t, nonMemberIDs)
+		require.NotNil(t, response)
+		assert.Contains(t, response.Text, "@poster, @reactor")
+	})
+
+	t.Run("add", func(t *testing.T) {
+		api := setupAPI(true)
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		nonMemberIDs, response, err := plugin.prepareNonMemberParticipants(participantsAdd, wpls, targetChannel, actingUserID)
+		require.NoError(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, []string{poster.Id, reactor.Id}, nonMemberIDs)
+		api.AssertNotCalled(t, "AddChannelMember", mock.Anything, mock.Anything)
+
+		plugin.finishNonMemberParticipants(participantsAdd, nonMemberIDs, targetChannel, "link")
+		api.AssertCalled(t, "AddChannelMember", targetChannel.Id, poster.Id)
+		api.AssertCalled(t, "AddChannelMember", targetChannel.Id, reactor.Id)
+		api.AssertNotCalled(t, "GetDirectChannel", mock.Anything, mock.Anything)
+	})
+
+	t.Run("participants who can't be added are notified", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("AddChannelMember", targetChannel.Id, poster.Id).Return(&model.ChannelMember{}, nil)
+		api.On("AddChannelMember", targetChannel.Id, reactor.Id).Return(nil, model.NewAppError("AddChannelMember", "app.error", nil, "", 500))
+		api.On("LogError", mock.AnythingOfTypeArgument("string"), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		api.On("GetDirectChannel", reactor.Id, mock.AnythingOfType("string")).Return(&model.Channel{Id: model.NewId()}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{}, nil)
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		plugin.finishNonMemberParticipants(participantsAdd, []string{poster.Id, reactor.Id}, targetChannel, "link")
+		api.AssertNotCalled(t, "GetDirectChannel", poster.Id, mock.Anything)
+		api.AssertCalled(t, "GetDirectChannel", reactor.Id, mock.Anything)
+	})
+
+	t.Run("add without permission", func(t *testing.T) {
+		api := setupAPI(false)
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		_, response, err := plugin.prepareNonMemberParticipants(participantsAdd, wpls, targetChannel, actingUserID)
+		require.NoError(t, err)
+		require.NotNil(t, response)
+		assert.Contains(t, response.Text, "you don't have permission to add members")
+		api.AssertNotCalled(t, "AddChannelMember", mock.Anything, mock.Anything)
+	})
+
+	t.Run("add to direct message channel", func(t *testing.T) {
+		var plugin Plugin
+		plugin.SetAPI(setupAPI(true))
+
+		_, response, err := plugin.prepareNonMemberParticipants(participantsAdd, wpls, directChannel, actingUserID)
+		require.NoError(t, err)
+		require.NotNil(t, response)
+		assert.Contains(t, response.Text, "can't be added to direct or group message channels")
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// relativePermalinkPrefix matches the start of the message or a character that
+// can't be part of a URL, so that relative permalinks are only matched when
+// they aren't the path of a link to another site.
+const relativePermalinkPrefix = `(^|[^\w.:/-])`
+
+// permalinkRewriter rewrites permalinks between the posts of a post list that
+// is being copied, so that they point at the new posts instead of the
+// original ones. Both absolute links starting with the site URL and relative
+// links starting with the team name are rewritten.
+type permalinkRewriter struct {
+	pattern  *regexp.Regexp
+	teamName string
+
+	// newPostIDs maps the IDs of original posts to the IDs of the posts
+	// created from them. It is filled in as new posts are created.
+	newPostIDs map[string]string
+}
+
+// newPermalinkRewriter returns a permalinkRewriter for copying the post list
+// to the target channel, or nil if none of the posts contain a permalink.
+func (p *Plugin) newPermalinkRewriter(wpl *WranglerPostList, targetChannel *model.Channel) (*permalinkRewriter, error) {
+	var hasPermalinks bool
+	for _, post := range wpl.Posts {
+		if strings.Contains(post.Message, "/pl/") {
+			hasPermalinks = true
+			break
+		}
+	}
+	if !hasPermalinks {
+		return nil, nil
+	}
+
+	prefix := "()" + relativePermalinkPrefix
+	config := p.API.GetConfig()
+	if config != nil && config.ServiceSettings.SiteURL != nil && len(*config.ServiceSettings.SiteURL) != 0 {
+		siteURL := strings.TrimSuffix(*config.ServiceSettings.SiteURL, "/")
+		prefix = "(?:(" + regexp.QuoteMeta(siteURL) + ")|" + relativePermalinkPrefix + ")"
+	}
+
+	// Direct and group message channels don't belong to a team, so links to
+	// them keep the team they already had.
+	var teamName string
+	if len(targetChannel.TeamId) != 0 {
+		team, appErr := p.API.GetTeam(targetChannel.TeamId)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to get team of target channel")
+		}
+		teamName = team.Name
+	}
+
+	return &permalinkRewriter{
+		pattern:    regexp.MustCompile(prefix + `/([A-Za-z0-9_\-]+)/pl/([a-z0-9]{26})\b`),
+		teamName:   teamName,
+		newPostIDs: make(map[string]string),
+	}, nil
+}
+
+// rewrite replaces the permalinks in the message that point at original
+// posts that have already been copied. Links to other posts are kept as they
+// are.
+func (r *permalinkRewriter) rewrite(message string) string {
+	return r.pattern.ReplaceAllStringFunc(message, func(link string) string {
+		match := r.pattern.FindStringSubmatch(link)
+		siteURL, prefix, teamName, postID := match[1], match[2], match[3], match[4]
+		newPostID, ok := r.newPostIDs[postID]
+		if !ok {
+			return link
+		}
+
+		if len(r.teamName) != 0 {
+			teamName = r.teamName
+		}
+
+		return prefix + makePostLink(siteURL, teamName, newPostID)
+	})
+}
+
+// rewriteLaterPermalinks updates the new posts that link to posts that were
+// copied after them. Links to earlier posts are rewritten before each post is
+// created, so only posts with links further down the thread are updated. The
+// server marks updated posts as edited, so the edit time each post was
+// created with is kept by MessageWillBeUpdated.
+func (p *Plugin) rewriteLaterPermalinks(rewriter *permalinkRewriter, newPosts []*model.Post) error {
+	for _, newPost := range newPosts {
+		message := rewriter.rewrite(newPost.Message)
+		if message == newPost.Message {
+			continue
+		}
+
+		newPost.Message = message
+		p.setPermalinkUpdate(newPost.Id, newPost.EditAt)
+		_, appErr := p.API.UpdatePost(newPost)
+		p.clearPermalinkUpdate(newPost.Id)
+		if appErr != nil {
+			return errors.Wrap(appErr, "unable to update permalinks in new post")
+		}
+	}
+
+	return nil
+}
+
+// setPermalinkUpdate records that the post is about to be updated by
+// rewriteLaterPermalinks, along with the edit time it must keep.
+func (p *Plugin) setPermalinkUpdate(postID string, editAt int64) {
+	p.permalinkUpdatesLock.Lock()
+	defer p.permalinkUpdatesLock.Unlock()
+
+	if p.permalinkUpdates == nil {
+		p.permalinkUpdates = make(map[string]int64)
+	}
+	p.permalinkUpdates[postID] = editAt
+}
+
+func (p *Plugin) clearPermalinkUpdate(postID string) {
+	p.permalinkUpdatesLock.Lock()
+	defer p.permalinkUpdatesLock.Unlock()
+
+	delete(p.permalinkUpdates, postID)
+}
+
+// MessageWillBeUpdated restores the edit time of posts whose permalinks are
+// being rewritten by Wrangler, so that they aren't shown as edited. Every
+// other update is left as it is.
+func (p *Plugin) MessageWillBeUpdated(c *plugin.Context, newPost, oldPost *model.Post) (*model.Post, string) {
+	p.permalinkUpdatesLock.Lock()
+	defer p.permalinkUpdatesLock.Unlock()
+
+	editAt, ok := p.permalinkUpdates[newPost.Id]
+	if ok {
+		newPost.EditAt = editAt
+	}
+
+	return newPost, ""
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyWranglerPostlistPermalinks(t *testing.T) {
+	siteURL := "https://chat.example.com"
+	targetTeam := &model.Team{Id: model.NewId(), Name: "target-team"}
+	targetChannel := &model.Channel{Id: model.NewId(), TeamId: targetTeam.Id}
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString(siteURL),
+		},
+	}
+
+	var createdPosts, updatedPosts []*model.Post
+	api := &plugintest.API{}
+	api.On("GetConfig").Return(config)
+	api.On("GetTeam", targetTeam.Id).Return(targetTeam, nil)
+	api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+		created := post.Clone()
+		created.Id = model.NewId()
+		createdPosts = append(createdPosts, created)
+		return created
+	}, nil)
+	var plugin Plugin
+	// The server marks updated posts as edited before running the
+	// MessageWillBeUpdated hook.
+	api.On("UpdatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+		updated := post.Clone()
+		updated.EditAt = model.GetMillis()
+		updated, _ = plugin.MessageWillBeUpdated(nil, updated, post)
+		updatedPosts = append(updatedPosts, updated)
+		return updated
+	}, nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(true, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+
+	plugin.SetAPI(api)
+
+	wpl := buildWranglerPostList(mockGenerateThread(3, model.NewId()))
+	outsidePostID := model.NewId()
+	wpl.Posts[0].Message = fmt.Sprintf("See the answer: %s", makePostLink(siteURL, "original-team", wpl.Posts[2].Id))
+	wpl.Posts[1].Message = fmt.Sprintf("As asked in %s/original-team/pl/%s and %s", siteURL, wpl.Posts[0].Id, makePostLink(siteURL, "other-team", outsidePostID))
+	wpl.Posts[2].Message = fmt.Sprintf("The answer, see [the question](/original-team/pl/%s)", wpl.Posts[0].Id)
+
+	_, err := plugin.copyWranglerPostlist(wpl, targetChannel, copyOptions{}, &WranglerJournal{ID: model.NewId()})
+	require.NoError(t, err)
+	require.Len(t, createdPosts, 3)
+
+	// Links to earlier posts are rewritten before the post is created.
+	assert.Equal(t, fmt.Sprintf("As asked in %s and %s",
+		makePostLink(siteURL, targetTeam.Name, createdPosts[0].Id),
+		makePostLink(siteURL, "other-team", outsidePostID),
+	), createdPosts[1].Message)
+
+	// Links to later posts are rewritten once those posts exist.
+	require.Len(t, updatedPosts, 1)
+	assert.Equal(t, createdPosts[0].Id, updatedPosts[0].Id)
+	assert.Equal(t, fmt.Sprintf("See the answer: %s", makePostLink(siteURL, targetTeam.Name, createdPosts[2].Id)), updatedPosts[0].Message)
+	assert.Equal(t, createdPosts[0].EditAt, updatedPosts[0].EditAt)
+
+	// Relative links are kept relative.
+	assert.Equal(t, fmt.Sprintf("The answer, see [the question](/%s/pl/%s)", targetTeam.Name, createdPosts[0].Id), createdPosts[2].Message)
+
+	t.Run("other updates are marked as edited", func(t *testing.T) {
+		editAt := model.GetMillis()
+		updated, rejection := plugin.MessageWillBeUpdated(nil, &model.Post{Id: createdPosts[0].Id, EditAt: editAt}, createdPosts[0])
+		assert.Empty(t, rejection)
+		assert.Equal(t, editAt, updated.EditAt)
+	})
+}
+
+func TestPermalinkRewriter(t *testing.T) {
+	oldID := model.NewId()
+	newID := model.NewId()
+	siteURL := "https://chat.example.com/mattermost"
+
+	var plugin Plugin
+	api := &plugintest.API{}
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: NewString(siteURL + "/")}})
+	plugin.SetAPI(api)
+
+	t.Run("no permalinks", func(t *testing.T) {
+		wpl := newWranglerPostListFromPosts([]*model.Post{{Id: model.NewId(), Message: "hello"}})
+		rewriter, err := plugin.newPermalinkRewriter(wpl, &model.Channel{Id: model.NewId()})
+		require.NoError(t, err)
+		assert.Nil(t, rewriter)
+	})
+
+	t.Run("channel without a team", func(t *testing.T) {
+		wpl := newWranglerPostListFromPosts([]*model.Post{{Id: model.NewId(), Message: makePostLink(siteURL, "team-a", oldID)}})
+		rewriter, err := plugin.newPermalinkRewriter(wpl, &model.Channel{Id: model.NewId(), Type: model.CHANNEL_DIRECT})
+		require.NoError(t, err)
+		require.NotNil(t, rewriter)
+		rewriter.newPostIDs[oldID] = newID
+
+		assert.Equal(t, makePostLink(siteURL, "team-a", newID), rewriter.rewrite(makePostLink(siteURL, "team-a", oldID)))
+		assert.Equal(t, "https://other.example.com/team-a/pl/"+oldID, rewriter.rewrite("https://other.example.com/team-a/pl/"+oldID))
+		assert.Equal(t, makePostLink(siteURL, "team-a", oldID)+"x", rewriter.rewrite(makePostLink(siteURL, "team-a", oldID)+"x"))
+		assert.Equal(t, "(/team-a/pl/"+newID+")", rewriter.rewrite("(/team-a/pl/"+oldID+")"))
+		assert.Equal(t, "/team-a/pl/"+newID, rewriter.rewrite("/team-a/pl/"+oldID))
+		assert.Equal(t, "example.com/team-a/pl/"+oldID, rewriter.rewrite("example.com/team-a/pl/"+oldID))
+	})
+
+	t.Run("no site URL", func(t *testing.T) {
+		var plugin Plugin
+		api := &plugintest.API{}
+		api.On("GetConfig").Return(&model.Config{})
+		plugin.SetAPI(api)
+
+		wpl := newWranglerPostListFromPosts([]*model.Post{{Id: model.NewId(), Message: "/team-a/pl/" + oldID}})
+		rewriter, err := plugin.newPermalinkRewriter(wpl, &model.Channel{Id: model.NewId(), Type: model.CHANNEL_DIRECT})
+		require.NoError(t, err)
+		require.NotNil(t, rewriter)
+		rewriter.newPostIDs[oldID] = newID
+
+		assert.Equal(t, "see /team-a/pl/"+newID, rewriter.rewrite("see /team-a/pl/"+oldID))
+		assert.Equal(t, makePostLink(siteURL, "team-a", oldID), rewriter.rewrite(makePostLink(siteURL, "team-a", oldID)))
+	})
+}
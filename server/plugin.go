@@ -2,6 +2,7 @@ package main
 
 import (
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -21,6 +22,23 @@ type Plugin struct {
 	// configuration is the active plugin configuration. Consult getConfiguration and
 	// setConfiguration for usage.
 	configuration *configuration
+
+	// jobWorkerStop is closed to stop the background job worker, and
+	// jobWorkerWake wakes the worker up when a job is queued.
+	jobWorkerStop chan struct{}
+	jobWorkerWake chan struct{}
+
+	// journalRecheckTimer resolves unfinished journals again once the ones
+	// that were still being updated have become stale. It is stopped when
+	// the plugin deactivates.
+	journalRecheckLock    sync.Mutex
+	journalRecheckTimer   *time.Timer
+	journalRecheckStopped bool
+
+	// permalinkUpdates maps the IDs of posts that are being updated by
+	// rewriteLaterPermalinks to the edit time they must keep.
+	permalinkUpdatesLock sync.Mutex
+	permalinkUpdates     map[string]int64
 }
 
 // BuildHash is the full git hash of the build.
@@ -56,5 +74,21 @@ func (p *Plugin) OnActivate() error {
 	}
 	p.BotUserID = botID
 
+	err = p.resolveUnfinishedJournals()
+	if err != nil {
+		p.API.LogError("Unable to resolve unfinished journals", "error", err.Error())
+	}
+
+	p.startJobWorker()
+
 	return p.API.RegisterCommand(getCommand(config.CommandAutoCompleteEnable))
 }
+
+// OnDeactivate runs when the plugin deactivates and stops the background job
+// worker and the journal recheck.
+func (p *Plugin) OnDeactivate() error {
+	p.stopJobWorker()
+	p.stopJournalRecheck()
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	propOriginalPostID    = "wrangler_original_post_id"
+	propOriginalChannelID = "wrangler_original_channel_id"
+	propOriginalTeamID    = "wrangler_original_team_id"
+	propOperation         = "wrangler_operation"
+	propActingUserID      = "wrangler_acting_user_id"
+	propWrangledAt        = "wrangler_wrangled_at"
+
+	provenanceBackwardKeyPrefix = "prov_b_"
+	provenanceForwardKeyPrefix  = "prov_f_"
+)
+
+// Provenance records where a post created by Wrangler came from.
+type Provenance struct {
+	PostID            string `json:"post_id"`
+	OriginalPostID    string `json:"original_post_id"`
+	OriginalChannelID string `json:"original_channel_id"`
+	OriginalTeamID    string `json:"original_team_id"`
+	Operation         string `json:"operation"`
+	UserID            string `json:"user_id"`
+	CreateAt          int64  `json:"create_at"`
+}
+
+func newProvenance(original *model.Post, originalTeamID, operation, userID string) *Provenance {
+	return &Provenance{
+		OriginalPostID:    original.Id,
+		OriginalChannelID: original.ChannelId,
+		OriginalTeamID:    originalTeamID,
+		Operation:         operation,
+		UserID:            userID,
+		CreateAt:          model.GetMillis(),
+	}
+}
+
+// addToPost stores the provenance in the post props. Any provenance props from
+// earlier operations are replaced; the full history is kept in the KV store.
+func (pr *Provenance) addToPost(post *model.Post) {
+	post.AddProp(propOriginalPostID, pr.OriginalPostID)
+	post.AddProp(propOriginalChannelID, pr.OriginalChannelID)
+	post.AddProp(propOriginalTeamID, pr.OriginalTeamID)
+	post.AddProp(propOperation, pr.Operation)
+	post.AddProp(propActingUserID, pr.UserID)
+	post.AddProp(propWrangledAt, pr.CreateAt)
+}
+
+// provenanceFromPost reads the provenance props of a post. Nil is returned if
+// the post was not created by Wrangler.
+func provenanceFromPost(post *model.Post) *Provenance {
+	originalPostID, ok := post.GetProp(propOriginalPostID).(string)
+	if !ok || len(originalPostID) == 0 {
+		return nil
+	}
+
+	pr := &Provenance{
+		PostID:         post.Id,
+		OriginalPostID: originalPostID,
+	}
+	pr.OriginalChannelID, _ = post.GetProp(propOriginalChannelID).(string)
+	pr.OriginalTeamID, _ = post.GetProp(propOriginalTeamID).(string)
+	pr.Operation, _ = post.GetProp(propOperation).(string)
+	pr.UserID, _ = post.GetProp(propActingUserID).(string)
+
+	// Numeric props are decoded as float64 when read back from the database.
+	switch wrangledAt := post.GetProp(propWrangledAt).(type) {
+	case int64:
+		pr.CreateAt = wrangledAt
+	case float64:
+		pr.CreateAt = int64(wrangledAt)
+	}
+
+	return pr
+}
+
+// recordProvenance stores the provenance of a new post in the KV store. The
+// backward index maps the new post to its provenance and the forward index
+// maps the original post to every post that was created from it. The
+// provenance is added to the journal first so that a rollback removes both
+// index entries again.
+func (p *Plugin) recordProvenance(journal *WranglerJournal, pr *Provenance) error {
+	err := p.journalProvenance(journal, pr)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(pr)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal provenance")
+	}
+	appErr := p.API.KVSet(provenanceBackwardKeyPrefix+pr.PostID, b)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to save provenance")
+	}
+
+	_, err = p.updateKVStringList(provenanceForwardKeyPrefix+pr.OriginalPostID, func(forwardIDs []string) []string {
+		return append(forwardIDs, pr.PostID)
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to save provenance forward index")
+	}
+
+	return nil
+}
+
+// removeProvenance removes the provenance of a post that was deleted by a
+// rollback from both the backward and forward indexes.
+func (p *Plugin) removeProvenance(postID, originalPostID string) error {
+	_, err := p.updateKVStringList(provenanceForwardKeyPrefix+originalPostID, func(forwardIDs []string) []string {
+		var remaining []string
+		for _, id := range forwardIDs {
+			if id != postID {
+				remaining = append(remaining, id)
+			}
+		}
+		return remaining
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to remove post from provenance forward index")
+	}
+
+	appErr := p.API.KVDelete(provenanceBackwardKeyPrefix + postID)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to remove provenance")
+	}
+
+	return nil
+}
+
+// getProvenance returns the provenance of a post from the KV store, falling
+// back to the post props if the post has no KV entry.
+func (p *Plugin) getProvenance(postID string) (*Provenance, error) {
+	b, appErr := p.API.KVGet(provenanceBackwardKeyPrefix + postID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get provenance")
+	}
+	if b != nil {
+		var pr Provenance
+		err := json.Unmarshal(b, &pr)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to unmarshal provenance")
+		}
+
+		return &pr, nil
+	}
+
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		// The post may have been deleted by a later move, in which case only
+		// the KV index can be used.
+		return nil, nil
+	}
+
+	return provenanceFromPost(post), nil
+}
+
+// getProvenanceForward returns the IDs of every post that was created from
+// the given post.
+func (p *Plugin) getProvenanceForward(postID string) ([]string, error) {
+	forwardIDs, _, err := p.getKVStringList(provenanceForwardKeyPrefix + postID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get provenance forward index")
+	}
+
+	return forwardIDs, nil
+}
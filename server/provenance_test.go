@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvenanceFromPost(t *testing.T) {
+	t.Run("not wrangled", func(t *testing.T) {
+		assert.Nil(t, provenanceFromPost(&model.Post{Id: model.NewId()}))
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		original := &model.Post{
+			Id:        model.NewId(),
+			ChannelId: model.NewId(),
+		}
+		pr := newProvenance(original, model.NewId(), operationMove, model.NewId())
+
+		post := &model.Post{Id: model.NewId()}
+		pr.addToPost(post)
+		pr.PostID = post.Id
+		assert.Equal(t, pr, provenanceFromPost(post))
+	})
+
+	t.Run("wrangled at decoded as float", func(t *testing.T) {
+		post := &model.Post{Id: model.NewId()}
+		post.AddProp(propOriginalPostID, model.NewId())
+		post.AddProp(propWrangledAt, float64(1234))
+
+		pr := provenanceFromPost(post)
+		require.NotNil(t, pr)
+		assert.Equal(t, int64(1234), pr.CreateAt)
+	})
+}
+
+func TestRecordProvenance(t *testing.T) {
+	originalPostID := model.NewId()
+	existingCopyID := model.NewId()
+	existing, err := json.Marshal([]string{existingCopyID})
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store[provenanceForwardKeyPrefix+originalPostID] = existing
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+		mock.AnythingOfTypeArgument("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	journal, err := plugin.startJournal(operationCopy, model.NewId())
+	require.NoError(t, err)
+
+	pr := &Provenance{
+		PostID:         model.NewId(),
+		OriginalPostID: originalPostID,
+		Operation:      operationCopy,
+	}
+	require.NoError(t, plugin.journalPost(journal, pr.PostID))
+	require.NoError(t, plugin.recordProvenance(journal, pr))
+	assert.Equal(t, map[string]string{pr.PostID: originalPostID}, journal.Provenance)
+
+	var savedPR Provenance
+	require.NoError(t, json.Unmarshal(store[provenanceBackwardKeyPrefix+pr.PostID], &savedPR))
+	assert.Equal(t, *pr, savedPR)
+
+	forwardIDs, err := plugin.getProvenanceForward(originalPostID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{existingCopyID, pr.PostID}, forwardIDs)
+
+	t.Run("rollback removes the provenance", func(t *testing.T) {
+		require.NoError(t, plugin.rollbackJournal(journal))
+
+		assert.Nil(t, store[provenanceBackwardKeyPrefix+pr.PostID])
+		forwardIDs, err := plugin.getProvenanceForward(originalPostID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{existingCopyID}, forwardIDs)
+		assert.Nil(t, store[journalKey(journal.ID)])
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	rateLimitKeyPrefix = "rate_"
+	rateLimitGlobalKey = "global"
+
+	// rateLimitWindow is the period rate limits are counted over. Counters
+	// are kept for a fixed window starting on the hour.
+	rateLimitWindow = time.Hour
+
+	rateLimitUpdateRetries = 5
+)
+
+// rateLimitDenial describes a rate limit that a command has hit.
+type rateLimitDenial struct {
+	operation string
+	limit     int
+	global    bool
+	resetAt   time.Time
+	retryIn   time.Duration
+}
+
+func (d *rateLimitDenial) message() string {
+	who := "You have"
+	if d.global {
+		who = "Wrangler users have"
+	}
+
+	minutes := int((d.retryIn + time.Minute - 1) / time.Minute)
+
+	return fmt.Sprintf("Rate limit reached. %s already run %d %s operations this hour, which is the most Wrangler is configured to allow. Try again after %s (in %d minutes).",
+		who, d.limit, d.operation, formatTimestamp(model.GetMillisForTime(d.resetAt)), minutes)
+}
+
+func rateLimitKey(operation, subject string, windowStart time.Time) string {
+	return fmt.Sprintf("%s%s_%s_%d", rateLimitKeyPrefix, operation, subject, windowStart.Unix()/int64(rateLimitWindow/time.Second))
+}
+
+// rateLimit is one of the limits an operation is counted against.
+type rateLimit struct {
+	subject string
+	limit   int
+	global  bool
+}
+
+// getRateLimits returns the per-user and global rate limits of the operation.
+func (p *Plugin) getRateLimits(operation, userID string) []rateLimit {
+	perUserLimit, globalLimit := p.getConfiguration().RateLimits(operation)
+
+	return []rateLimit{
+		{userID, perUserLimit, false},
+		{rateLimitGlobalKey, globalLimit, true},
+	}
+}
+
+// checkRateLimit counts an operation run by the user against the per-user and
+// global rate limits of the operation. A denial is returned if either limit
+// has been reached, in which case the operation isn't counted against either
+// limit. The per-user limit is checked first so that users who have reached
+// it can't use up the global limit.
+func (p *Plugin) checkRateLimit(operation, userID string, now time.Time) (*rateLimitDenial, error) {
+	windowStart := now.Truncate(rateLimitWindow)
+
+	var countedKeys []string
+	for _, l := range p.getRateLimits(operation, userID) {
+		if l.limit == 0 {
+			continue
+		}
+
+		key := rateLimitKey(operation, l.subject, windowStart)
+		counted, err := p.incrementRateLimitCounter(key, l.limit)
+		if err != nil {
+			p.decrementRateLimitCounters(countedKeys)
+			return nil, err
+		}
+		if counted {
+			countedKeys = append(countedKeys, key)
+		} else {
+			p.decrementRateLimitCounters(countedKeys)
+			return &rateLimitDenial{
+				operation: operation,
+				limit:     l.limit,
+				global:    l.global,
+				resetAt:   windowStart.Add(rateLimitWindow),
+				retryIn:   windowStart.Add(rateLimitWindow).Sub(now),
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// refundRateLimit takes back an operation counted by checkRateLimit at the
+// same time, for commands that didn't go on to change any messages.
+func (p *Plugin) refundRateLimit(operation, userID string, now time.Time) {
+	windowStart := now.Truncate(rateLimitWindow)
+
+	var keys []string
+	for _, l := range p.getRateLimits(operation, userID) {
+		if l.limit != 0 {
+			keys = append(keys, rateLimitKey(operation, l.subject, windowStart))
+		}
+	}
+	p.decrementRateLimitCounters(keys)
+}
+
+// decrementRateLimitCounters takes one away from each of the counters. Failing
+// to do so only leaves the limits stricter than they should be for the rest
+// of the window, so errors are logged.
+func (p *Plugin) decrementRateLimitCounters(keys []string) {
+	for _, key := range keys {
+		err := p.decrementRateLimitCounter(key)
+		if err != nil {
+			p.API.LogError("Unable to refund rate limit counter", "key", key, "error", err.Error())
+		}
+	}
+}
+
+// incrementRateLimitCounter atomically increments the counter stored under the
+// key unless it has already reached the limit. Counters are stored in the KV
+// store so that limits apply to every server in a cluster, and expire once
+// their window is over.
+func (p *Plugin) incrementRateLimitCounter(key string, limit int) (bool, error) {
+	for i := 0; i < rateLimitUpdateRetries; i++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return false, errors.Wrap(appErr, "unable to get rate limit counter")
+		}
+
+		var count int
+		if oldValue != nil {
+			count, _ = strconv.Atoi(string(oldValue))
+		}
+		if count >= limit {
+			return false, nil
+		}
+
+		saved, appErr := p.API.KVSetWithOptions(key, []byte(strconv.Itoa(count+1)), model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        oldValue,
+			ExpireInSeconds: int64(2 * rateLimitWindow / time.Second),
+		})
+		if appErr != nil {
+			return false, errors.Wrap(appErr, "unable to save rate limit counter")
+		}
+		if saved {
+			return true, nil
+		}
+	}
+
+	return false, errors.Errorf("unable to update rate limit counter %s as it is being changed by another server", key)
+}
+
+// decrementRateLimitCounter atomically decrements the counter stored under the
+// key, without going below zero.
+func (p *Plugin) decrementRateLimitCounter(key string) error {
+	for i := 0; i < rateLimitUpdateRetries; i++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return errors.Wrap(appErr, "unable to get rate limit counter")
+		}
+
+		var count int
+		if oldValue != nil {
+			count, _ = strconv.Atoi(string(oldValue))
+		}
+		if count <= 0 {
+			return nil
+		}
+
+		saved, appErr := p.API.KVSetWithOptions(key, []byte(strconv.Itoa(count-1)), model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        oldValue,
+			ExpireInSeconds: int64(2 * rateLimitWindow / time.Second),
+		})
+		if appErr != nil {
+			return errors.Wrap(appErr, "unable to save rate limit counter")
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.Errorf("unable to update rate limit counter %s as it is being changed by another server", key)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRateLimit(t *testing.T) {
+	user1 := model.NewId()
+	user2 := model.NewId()
+	user3 := model.NewId()
+	now := time.Date(2020, 6, 1, 10, 45, 0, 0, time.UTC)
+
+	setupPlugin := func(config *configuration) *Plugin {
+		api := &plugintest.API{}
+		mockKVStore(api)
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+		plugin.setConfiguration(config)
+
+		return &plugin
+	}
+
+	t.Run("no limits", func(t *testing.T) {
+		plugin := setupPlugin(&configuration{})
+		for i := 0; i < 50; i++ {
+			denial, err := plugin.checkRateLimit(operationMove, user1, now)
+			require.NoError(t, err)
+			require.Nil(t, denial)
+		}
+	})
+
+	t.Run("per-user limit", func(t *testing.T) {
+		plugin := setupPlugin(&configuration{MoveRateLimitPerUser: "2"})
+
+		for i := 0; i < 2; i++ {
+			denial, err := plugin.checkRateLimit(operationMove, user1, now)
+			require.NoError(t, err)
+			require.Nil(t, denial)
+		}
+
+		denial, err := plugin.checkRateLimit(operationMove, user1, now)
+		require.NoError(t, err)
+		require.NotNil(t, denial)
+		assert.False(t, denial.global)
+		assert.Equal(t, "Rate limit reached. You have already run 2 move operations this hour, which is the most Wrangler is configured to allow. Try again after 2020-06-01 11:00:00 UTC (in 15 minutes).", denial.message())
+
+		t.Run("other users and operations are not limited", func(t *testing.T) {
+			denial, err = plugin.checkRateLimit(operationMove, user2, now)
+			require.NoError(t, err)
+			assert.Nil(t, denial)
+
+			denial, err = plugin.checkRateLimit(operationCopy, user1, now)
+			require.NoError(t, err)
+			assert.Nil(t, denial)
+		})
+
+		t.Run("next window", func(t *testing.T) {
+			denial, err = plugin.checkRateLimit(operationMove, user1, now.Add(15*time.Minute))
+			require.NoError(t, err)
+			assert.Nil(t, denial)
+		})
+	})
+
+	t.Run("global limit", func(t *testing.T) {
+		plugin := setupPlugin(&configuration{CopyRateLimitPerUser: "1", CopyRateLimitGlobal: "2"})
+
+		denial, err := plugin.checkRateLimit(operationCopy, user1, now)
+		require.NoError(t, err)
+		require.Nil(t, denial)
+
+		// Users who have reached their own limit don't use up the global limit.
+		for i := 0; i < 3; i++ {
+			denial, err = plugin.checkRateLimit(operationCopy, user1, now)
+			require.NoError(t, err)
+			require.NotNil(t, denial)
+			assert.False(t, denial.global)
+		}
+
+		denial, err = plugin.checkRateLimit(operationCopy, user2, now)
+		require.NoError(t, err)
+		require.Nil(t, denial)
+
+		denial, err = plugin.checkRateLimit(operationCopy, user3, now)
+		require.NoError(t, err)
+		require.NotNil(t, denial)
+		assert.True(t, denial.global)
+		assert.Contains(t, denial.message(), "Wrangler users have already run 2 copy operations this hour")
+	})
+
+	t.Run("command is refused", func(t *testing.T) {
+		p := setupPlugin(&configuration{AttachRateLimitPerUser: "1"})
+
+		denial, err := p.checkRateLimit(operationAttach, user1, time.Now())
+		require.NoError(t, err)
+		require.Nil(t, denial)
+
+		resp, appErr := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+			UserId:  user1,
+			Command: "wrangler detach message " + model.NewId(),
+		})
+		require.Nil(t, appErr)
+		assert.Contains(t, resp.Text, "Rate limit reached. You have already run 1 attach operations this hour")
+	})
+
+	t.Run("global denial doesn't count against the user", func(t *testing.T) {
+		plugin := setupPlugin(&configuration{CopyRateLimitPerUser: "1", CopyRateLimitGlobal: "1"})
+
+		denial, err := plugin.checkRateLimit(operationCopy, user1, now)
+		require.NoError(t, err)
+		require.Nil(t, denial)
+
+		denial, err = plugin.checkRateLimit(operationCopy, user2, now)
+		require.NoError(t, err)
+		require.NotNil(t, denial)
+		assert.True(t, denial.global)
+
+		plugin.refundRateLimit(operationCopy, user1, now)
+
+		denial, err = plugin.checkRateLimit(operationCopy, user2, now)
+		require.NoError(t, err)
+		assert.Nil(t, denial)
+	})
+
+	t.Run("refunded operations are not counted", func(t *testing.T) {
+		plugin := setupPlugin(&configuration{MoveRateLimitPerUser: "1"})
+
+		denial, err := plugin.checkRateLimit(operationMove, user1, now)
+		require.NoError(t, err)
+		require.Nil(t, denial)
+
+		plugin.refundRateLimit(operationMove, user1, now)
+		plugin.refundRateLimit(operationMove, user1, now)
+
+		denial, err = plugin.checkRateLimit(operationMove, user1, now)
+		require.NoError(t, err)
+		require.Nil(t, denial)
+
+		denial, err = plugin.checkRateLimit(operationMove, user1, now)
+		require.NoError(t, err)
+		require.NotNil(t, denial)
+	})
+
+	t.Run("usage errors are not counted", func(t *testing.T) {
+		p := setupPlugin(&configuration{AttachRateLimitPerUser: "1"})
+
+		for i := 0; i < 2; i++ {
+			resp, appErr := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+				UserId:  user1,
+				Command: "wrangler detach message",
+			})
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Error: missing arguments")
+		}
+
+		denial, err := p.checkRateLimit(operationAttach, user1, time.Now())
+		require.NoError(t, err)
+		require.Nil(t, denial)
+	})
+
+	t.Run("undo is counted against the undone operation", func(t *testing.T) {
+		p := setupPlugin(&configuration{CopyRateLimitPerUser: "1"})
+		p.saveUndoRecord(&UndoRecord{
+			Operation:       operationCopy,
+			UserID:          user1,
+			CreateAt:        model.GetMillis(),
+			TargetChannelID: model.NewId(),
+		})
+
+		denial, err := p.checkRateLimit(operationCopy, user1, time.Now())
+		require.NoError(t, err)
+		require.Nil(t, denial)
+
+		resp, appErr := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+			UserId:  user1,
+			Command: "wrangler undo",
+		})
+		require.Nil(t, appErr)
+		assert.Contains(t, resp.Text, "Rate limit reached. You have already run 1 copy operations this hour")
+	})
+}
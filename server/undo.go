@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	undoKeyPrefix = "undo_"
+
+	// defaultUndoWindow is how long an operation can be undone for when the
+	// plugin configuration doesn't set a window.
+	defaultUndoWindow = 10 * time.Minute
+)
+
+// UndoRecord is the information needed to undo the most recent move, copy or
+// attach run by a user. Only the latest operation of each user is kept.
+type UndoRecord struct {
+	Operation string `json:"operation"`
+	UserID    string `json:"user_id"`
+	CreateAt  int64  `json:"create_at"`
+
+	// OriginalChannelID is the channel the messages were wrangled from and
+	// TargetChannelID is the channel they were wrangled to.
+	OriginalChannelID string `json:"original_channel_id"`
+	TargetChannelID   string `json:"target_channel_id"`
+
+	// NewRootPostID is the root of the thread created by a move or copy, or
+	// the reply created by an attach. NewPostIDs are every post created by
+	// the operation.
+	NewRootPostID string   `json:"new_root_post_id"`
+	NewPostIDs    []string `json:"new_post_ids"`
+
+	// OriginalCreateAts maps each post created by a move to the creation time
+	// of the post it replaced, so that an undo puts the messages back where
+	// they were in the original channel.
+	OriginalCreateAts map[string]int64 `json:"original_create_ats"`
+}
+
+func undoKey(userID string) string {
+	return undoKeyPrefix + userID
+}
+
+// newThreadUndoRecord builds the undo record of a journaled move or copy of a
+// post list.
+func newThreadUndoRecord(operation, userID string, originalChannel, targetChannel *model.Channel, wpl *WranglerPostList, journal *WranglerJournal) *UndoRecord {
+	record := &UndoRecord{
+		Operation:         operation,
+		UserID:            userID,
+		CreateAt:          model.GetMillis(),
+		OriginalChannelID: originalChannel.Id,
+		TargetChannelID:   targetChannel.Id,
+		NewPostIDs:        journal.PostIDs,
+		OriginalCreateAts: make(map[string]int64),
+	}
+	if len(journal.PostIDs) != 0 {
+		record.NewRootPostID = journal.PostIDs[0]
+	}
+
+	// The journal starts with the copy of each post in the post list, in the
+	// same order.
+	for i, post := range wpl.Posts {
+		if i < len(journal.PostIDs) {
+			record.OriginalCreateAts[journal.PostIDs[i]] = post.CreateAt
+		}
+	}
+
+	return record
+}
+
+// isExpired returns if the operation is too old to be undone.
+func (r *UndoRecord) isExpired(now int64, window time.Duration) bool {
+	return now-r.CreateAt > int64(window/time.Millisecond)
+}
+
+// saveUndoRecord stores the record as the user's most recent undoable
+// operation. The operation has already finished, so failures are only logged.
+func (p *Plugin) saveUndoRecord(record *UndoRecord) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		p.API.LogError("Unable to marshal undo record", "error", err.Error())
+		return
+	}
+
+	appErr := p.API.KVSet(undoKey(record.UserID), b)
+	if appErr != nil {
+		p.API.LogError("Unable to save undo record",
+			"user_id", record.UserID,
+			"error", appErr.Error(),
+		)
+	}
+}
+
+// clearUndoRecord removes the user's undo record after an operation that
+// can't be undone, so that an undo never reverts an operation older than the
+// user's most recent one. The operation has already finished, so failures are
+// only logged.
+func (p *Plugin) clearUndoRecord(userID string) {
+	appErr := p.API.KVDelete(undoKey(userID))
+	if appErr != nil {
+		p.API.LogError("Unable to clear undo record",
+			"user_id", userID,
+			"error", appErr.Error(),
+		)
+	}
+}
+
+// getUndoRecord returns the user's most recent undoable operation without
+// removing it. A nil record is returned if there is nothing to undo.
+func (p *Plugin) getUndoRecord(userID string) (*UndoRecord, error) {
+	b, appErr := p.API.KVGet(undoKey(userID))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get undo record")
+	}
+	if b == nil {
+		return nil, nil
+	}
+
+	var record UndoRecord
+	err := json.Unmarshal(b, &record)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal undo record")
+	}
+
+	return &record, nil
+}
+
+// takeUndoRecord removes and returns the user's most recent undoable
+// operation. The record is removed atomically so that an operation is only
+// undone once. A nil record is returned if there is nothing to undo.
+func (p *Plugin) takeUndoRecord(userID string) (*UndoRecord, error) {
+	b, appErr := p.API.KVGet(undoKey(userID))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get undo record")
+	}
+	if b == nil {
+		return nil, nil
+	}
+
+	deleted, appErr := p.API.KVCompareAndDelete(undoKey(userID), b)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to remove undo record")
+	}
+	if !deleted {
+		// The operation is being undone by another command.
+		return nil, nil
+	}
+
+	var record UndoRecord
+	err := json.Unmarshal(b, &record)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal undo record")
+	}
+
+	return &record, nil
+}
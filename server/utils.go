@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/spf13/pflag"
 )
 
 func makePostLink(siteURL, teamName, postID string) string {
@@ -43,6 +45,53 @@ func trimMessage(message string, trimLength int) string {
 	return fmt.Sprintf("%s...", message[:trimLength])
 }
 
+// getBoolFlagWithDefault returns the value of a bool flag if it was set and
+// the provided default value otherwise.
+func getBoolFlagWithDefault(flagSet *pflag.FlagSet, name string, defaultValue bool) (bool, error) {
+	if !flagSet.Changed(name) {
+		return defaultValue, nil
+	}
+
+	return flagSet.GetBool(name)
+}
+
+// parseTimeFlag parses a time given either as an RFC 3339 timestamp or as a
+// duration before now, such as "2h". The result is in milliseconds.
+func parseTimeFlag(value string, now time.Time) (int64, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return model.GetMillisForTime(t), nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%q is not an RFC 3339 timestamp or a positive duration", value)
+	}
+
+	return model.GetMillisForTime(now.Add(-duration)), nil
+}
+
+// formatBytes returns a human readable size, such as "1.5 MB".
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatTimestamp returns a millisecond timestamp as a UTC time.
+func formatTimestamp(millis int64) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04:05 MST")
+}
+
 func prettyPrintJSON(in string) string {
 	var out bytes.Buffer
 	err := json.Indent(&out, []byte(in), "", "\t")
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"sort"
+
 	"github.com/mattermost/mattermost-server/v5/model"
 )
 
@@ -12,6 +14,7 @@ type WranglerPostList struct {
 	EarlistPostTimestamp int64
 	LatestPostTimestamp  int64
 	FileAttachmentCount  int64
+	PinnedPostCount      int
 }
 
 // NumPosts returns the number of posts in a post list.
@@ -33,9 +36,48 @@ func (wpl *WranglerPostList) ContainsFileAttachments() bool {
 	return wpl.FileAttachmentCount != 0
 }
 
-func buildWranglerPostList(postList *model.PostList) *WranglerPostList {
+// SliceByTimestamp returns a new post list containing the posts created
+// between the start and end timestamps, inclusive. An end timestamp of 0 or
+// less includes all posts after the start.
+func (wpl *WranglerPostList) SliceByTimestamp(start, end int64) *WranglerPostList {
+	var posts []*model.Post
+	for _, post := range wpl.Posts {
+		if post.CreateAt < start {
+			continue
+		}
+		if end > 0 && post.CreateAt > end {
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	return newWranglerPostListFromPosts(posts)
+}
+
+// IsFullThread returns if the post list starts with the root post of a
+// thread, as opposed to only containing some of a thread's replies.
+func (wpl *WranglerPostList) IsFullThread() bool {
+	return wpl.NumPosts() != 0 && len(wpl.RootPost().RootId) == 0
+}
+
+// buildWranglerPostList builds a WranglerPostList from one or more post lists.
+// When multiple post lists are provided, they are combined and sorted by
+// CreateAt as a single list.
+func buildWranglerPostList(postLists ...*model.PostList) *WranglerPostList {
 	wpl := &WranglerPostList{}
 
+	if len(postLists) == 0 {
+		return wpl
+	}
+
+	postList := postLists[0]
+	if len(postLists) > 1 {
+		postList = model.NewPostList()
+		for _, pl := range postLists {
+			postList.Extend(pl)
+		}
+	}
+
 	postList.UniqueOrder()
 	postList.SortByCreateAt()
 	posts := postList.ToSlice()
@@ -45,12 +87,74 @@ func buildWranglerPostList(postList *model.PostList) *WranglerPostList {
 		return wpl
 	}
 
+	// The post list is sorted newest first, so reverse it.
+	sortedPosts := make([]*model.Post, len(posts))
+	for i := range posts {
+		sortedPosts[i] = posts[len(posts)-i-1]
+	}
+
+	return newWranglerPostListFromPosts(sortedPosts)
+}
+
+// buildThreadWranglerPostLists groups the posts of a channel post list, such
+// as pages from GetPostsForChannel, into threads. A WranglerPostList is
+// returned for every thread with a root post created between the start and end
+// timestamps, inclusive, oldest thread first. An end timestamp of 0 or less
+// includes all threads after the start. System messages are skipped.
+func buildThreadWranglerPostLists(postList *model.PostList, start, end int64) []*WranglerPostList {
+	var rootPosts []*model.Post
+	threadPosts := make(map[string][]*model.Post)
+
+	postList.UniqueOrder()
+	for _, postID := range postList.Order {
+		post, ok := postList.Posts[postID]
+		if !ok || post.IsSystemMessage() {
+			continue
+		}
+
+		if len(post.RootId) != 0 {
+			threadPosts[post.RootId] = append(threadPosts[post.RootId], post)
+			continue
+		}
+		if post.CreateAt < start {
+			continue
+		}
+		if end > 0 && post.CreateAt > end {
+			continue
+		}
+		rootPosts = append(rootPosts, post)
+		threadPosts[post.Id] = append(threadPosts[post.Id], post)
+	}
+
+	sort.Slice(rootPosts, func(i, j int) bool {
+		return rootPosts[i].CreateAt < rootPosts[j].CreateAt
+	})
+
+	var wpls []*WranglerPostList
+	for _, rootPost := range rootPosts {
+		posts := threadPosts[rootPost.Id]
+		sort.Slice(posts, func(i, j int) bool {
+			return posts[i].CreateAt < posts[j].CreateAt
+		})
+		wpls = append(wpls, newWranglerPostListFromPosts(posts))
+	}
+
+	return wpls
+}
+
+// newWranglerPostListFromPosts builds a WranglerPostList from posts that are
+// already sorted oldest first.
+func newWranglerPostListFromPosts(posts []*model.Post) *WranglerPostList {
+	wpl := &WranglerPostList{}
+
+	if len(posts) == 0 {
+		return wpl
+	}
+
 	// A separate ID key map to ensure no duplicates.
 	idKeys := make(map[string]bool)
 
-	for i := range posts {
-		p := posts[len(posts)-i-1]
-
+	for _, p := range posts {
 		// Add UserID to metadata if it's new.
 		if _, ok := idKeys[p.UserId]; !ok {
 			idKeys[p.UserId] = true
@@ -58,6 +162,9 @@ func buildWranglerPostList(postList *model.PostList) *WranglerPostList {
 		}
 
 		wpl.FileAttachmentCount += int64(len(p.FileIds))
+		if p.IsPinned {
+			wpl.PinnedPostCount++
+		}
 
 		wpl.Posts = append(wpl.Posts, p)
 	}
@@ -21,12 +21,22 @@ const setupUILater = (registry: PluginRegistry, store: Store<object, Action<obje
 
     const settings = await store.dispatch(getSettings());
 
-    if (settings.data.enable_web_ui) {
+    if (!settings.data.enable_web_ui) {
+        return;
+    }
+
+    const allowedOperations = settings.data.allowed_operations || [];
+
+    if (allowedOperations.includes('move')) {
         registry.registerRootComponent(MoveThreadModal);
-        registry.registerLeftSidebarHeaderComponent(LeftSidebarAttachMessage);
-        registry.registerLeftSidebarHeaderComponent(LeftSidebarCopyToChannel);
         registry.registerPostDropdownMenuComponent(MoveThreadDropdown);
+    }
+    if (allowedOperations.includes('attach')) {
+        registry.registerLeftSidebarHeaderComponent(LeftSidebarAttachMessage);
         registry.registerPostDropdownMenuComponent(AttachMessageDropdown);
+    }
+    if (allowedOperations.includes('copy')) {
+        registry.registerLeftSidebarHeaderComponent(LeftSidebarCopyToChannel);
         registry.registerPostDropdownMenuComponent(CopyToChannelDropdown);
         registry.registerChannelHeaderMenuAction(
             'Copy Messages to Channel',
@@ -4,6 +4,7 @@ import id from '../plugin_id';
 
 export type Settings = {
     enable_web_ui: boolean;
+    allowed_operations: Array<string>;
 }
 
 export type Channels = Array<Channel>